@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/doug-martin/goqu/v9"
@@ -164,18 +165,52 @@ func (d Driver) IsSame(target Driver) bool {
 	return strings.EqualFold(d.String(), target.String())
 }
 
+// GoquDialect 获取goqu方言,内部使用的 sqlite 驱动名称映射到 sqlite3 方言,保证字面量按sqlite规则转义
 func (d Driver) GoquDialect() goqu.DialectWrapper {
+	if d.IsSame(_Driver_sqlite) {
+		return goqu.Dialect(Driver_sqlite3.String())
+	}
 	return goqu.Dialect(d.String())
 }
 
+// EscapeString 按驱动转义字符串字面量(不含两侧单引号),未注册转义函数的驱动原样返回
 func (d Driver) EscapeString(val string) string {
-	switch strings.ToLower(d.String()) {
-	case strings.ToLower(Driver_mysql.String()):
-		return MysqlEscapeString(val)
-	case strings.ToLower(Driver_sqlite3.String()):
-		return SQLite3EscapeString(val)
+	fn, ok := d.Escaper()
+	if !ok {
+		return val
 	}
-	return val
+	return fn(val)
+}
+
+// EscapeStringFn 字符串字面量转义函数,转义后的值可直接放入单引号中
+type EscapeStringFn func(val string) string
+
+var driverEscapers = struct {
+	sync.RWMutex
+	fns map[string]EscapeStringFn
+}{fns: map[string]EscapeStringFn{}}
+
+// RegisterEscaper 为驱动设置字符串转义函数,可替换内置的 mysql、sqlite 转义;生成 sql 期间也可安全调用
+func RegisterEscaper(driver Driver, fn EscapeStringFn) {
+	driverEscapers.Lock()
+	defer driverEscapers.Unlock()
+	driverEscapers.fns[strings.ToLower(driver.String())] = fn
+}
+
+// Escaper 获取驱动字符串转义函数,驱动名按小写匹配
+func (d Driver) Escaper() (fn EscapeStringFn, ok bool) {
+	driverEscapers.RLock()
+	defer driverEscapers.RUnlock()
+	fn, ok = driverEscapers.fns[strings.ToLower(d.String())]
+	return fn, ok
+}
+
+func init() {
+	// 通过闭包引用,兼容外部覆盖 MysqlEscapeString、SQLite3EscapeString 变量
+	RegisterEscaper(Driver_mysql, func(val string) string { return MysqlEscapeString(val) })
+	sqlite3Escaper := func(val string) string { return SQLite3EscapeString(val) }
+	RegisterEscaper(Driver_sqlite3, sqlite3Escaper)
+	RegisterEscaper(_Driver_sqlite, sqlite3Escaper)
 }
 
 type Expressions = []goqu.Expression
@@ -221,7 +256,11 @@ var MysqlEscapeString = func(val string) string {
 	return string(dest)
 
 }
-var SQLite3EscapeString = MysqlEscapeString // 此处暂时使用mysql的
+
+// SQLite3EscapeString sqlite 不把反斜杠作为转义符,单引号需写成两个单引号
+var SQLite3EscapeString = func(val string) string {
+	return strings.ReplaceAll(val, "'", "''")
+}
 
 // Deprecated: 废弃，使用 Driver.GoquDialect 代替
 type DialectWrapper struct {
@@ -240,13 +279,7 @@ func (d DialectWrapper) DialectWrapper() goqu.DialectWrapper {
 
 // Deprecated: 废弃，使用 Driver.EscapeString 代替
 func (d DialectWrapper) EscapeString(val string) string {
-	switch strings.ToLower(d.dialect) {
-	case strings.ToLower(Driver_mysql.String()):
-		return MysqlEscapeString(val)
-	case strings.ToLower(Driver_sqlite3.String()):
-		return SQLite3EscapeString(val)
-	}
-	return val
+	return Driver(d.dialect).EscapeString(val)
 }
 
 // Deprecated: 废弃，使用 Driver
@@ -368,6 +401,12 @@ func (p *InsertParam) ApplyCustomFn(customFns ...CustomFnInsertParam) *InsertPar
 }
 
 func (p InsertParam) ToSQL(fs Fields) (sql string, err error) {
+	sql, _, err = p.toSQL(fs)
+	return sql, err
+}
+
+// toSQL 同时返回写入数据,供执行前校验外键
+func (p InsertParam) toSQL(fs Fields) (sql string, rowData any, err error) {
 	tableConfig := p.GetTable()
 	fs = fs.Builder(p.context, SCENE_SQL_INSERT, tableConfig, p.customFieldsFns) // 使用复制变量,后续正对场景的舒适化处理不会影响原始变量
 
@@ -376,25 +415,25 @@ func (p InsertParam) ToSQL(fs Fields) (sql string, err error) {
 	// 	return "", err
 	// }
 
-	rowData, err := fs.Data(layer_order...)
+	rowData, err = fs.Data(layer_order...)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	if IsNil(rowData) {
 		err = errors.New("InsertParam.Data() return nil data")
-		return "", err
+		return "", nil, err
 	}
 	ds := p.GetGoquDialect().Insert(tableConfig.Name).Rows(rowData)
 	sql, _, err = ds.ToSQL()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	if p.insertIgnore {
 		// 替换前缀为 INSERT IGNORE
 		sql = replaceInsertWithInsertIgnore(sql)
 	}
 	p.Log(sql)
-	return sql, nil
+	return sql, rowData, nil
 }
 
 func replaceInsertWithInsertIgnore(sql string) string {
@@ -415,6 +454,13 @@ func (p InsertParam) Validate() (err error) {
 }
 
 func (p InsertParam) Exec() (err error) {
+	if p._Table.isSharded() {
+		table, err := p._Table.routeShardedTable(p._Fields)
+		if err != nil {
+			return err
+		}
+		p._Table = table
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "InsertParam.exec",
@@ -434,7 +480,11 @@ func (p InsertParam) Exec() (err error) {
 	return nil
 }
 func (p InsertParam) exec(fsRef *Fields) (err error) {
-	sql, err := p.ToSQL(*fsRef)
+	sql, rowData, err := p.toSQL(*fsRef)
+	if err != nil {
+		return err
+	}
+	err = p.GetTable().CheckForeignKeys(rowData)
 	if err != nil {
 		return err
 	}
@@ -446,7 +496,7 @@ func (p InsertParam) exec(fsRef *Fields) (err error) {
 	})
 	lastInsertId, rowsAffected, err := withEventHandler.InsertWithLastId(sql)
 	if err != nil {
-		return err
+		return p.GetTable().TranslateError(err)
 	}
 	// 输入exec 函数无需返回，但是不能保证组合的中间件不会用到这2个字段，所以这里先追加
 	*fsRef = fsRef.Append(
@@ -462,6 +512,13 @@ func (p InsertParam) InsertWithLastId() (lastInsertId uint64, rowsAffected int64
 }
 
 func (p InsertParam) Insert() (lastInsertId uint64, rowsAffected int64, err error) {
+	if p._Table.isSharded() {
+		table, err := p._Table.routeShardedTable(p._Fields)
+		if err != nil {
+			return 0, 0, err
+		}
+		p._Table = table
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "InsertParam.Insert",
@@ -483,7 +540,11 @@ func (p InsertParam) Insert() (lastInsertId uint64, rowsAffected int64, err erro
 }
 
 func (p InsertParam) insert(fs Fields) (lastInsertId uint64, rowsAffected int64, err error) {
-	sql, err := p.ToSQL(fs)
+	sql, rowData, err := p.toSQL(fs)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = p.GetTable().CheckForeignKeys(rowData)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -493,7 +554,11 @@ func (p InsertParam) insert(fs Fields) (lastInsertId uint64, rowsAffected int64,
 			p.Log(sql, err)
 		}
 	})
-	return withEventHandler.InsertWithLastId(sql)
+	lastInsertId, rowsAffected, err = withEventHandler.InsertWithLastId(sql)
+	if err != nil {
+		return 0, 0, p.GetTable().TranslateError(err)
+	}
+	return lastInsertId, rowsAffected, nil
 }
 
 type BatchInsertParam struct {
@@ -548,14 +613,33 @@ func (p *BatchInsertParam) ApplyCustomFn(customFns ...CustomFnBatchInsertParam)
 }
 
 func (is BatchInsertParam) ToSQL() (sql string, err error) {
-	data := make([]any, 0)
+	if is._Table.isSharded() {
+		batches, err := is.shardedBatches()
+		if err != nil {
+			return "", err
+		}
+		if len(batches) > 1 { // 单条 sql 无法写入多张分表
+			err = errors.WithMessagef(ErrShardedTableRoute, "table:%s batch insert rows route to %d sharded tables,use Exec instead", is._Table.Name, len(batches))
+			return "", err
+		}
+		if len(batches) == 1 {
+			is = batches[0]
+		}
+	}
+	sql, _, err = is.toSQL()
+	return sql, err
+}
+
+// toSQL 同时返回写入数据,供执行前校验外键
+func (is BatchInsertParam) toSQL() (sql string, data []any, err error) {
+	data = make([]any, 0)
 
 	tableConfig := is.GetTable()
 	for _, fields := range is.rowFields {
 		fs := fields.Builder(is.context, SCENE_SQL_INSERT, tableConfig, is.customFieldsFns) // 使用复制变量,后续正对场景的舒适化处理不会影响原始变量
 		rowData, err := fs.Data(layer_order...)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		if IsNil(rowData) {
 			continue
@@ -563,43 +647,51 @@ func (is BatchInsertParam) ToSQL() (sql string, err error) {
 		data = append(data, rowData)
 	}
 	if len(data) == 0 {
-		return "", ErrBatchInsertDataIsNil
+		return "", nil, ErrBatchInsertDataIsNil
 	}
 	ds := is.GetGoquDialect().Insert(tableConfig.Name).Rows(data...)
 	sql, _, err = ds.ToSQL()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	is.Log(sql)
-	return sql, nil
+	return sql, data, nil
 }
 func (p BatchInsertParam) Exec() (err error) {
-	sql, err := p.ToSQL()
-	if err != nil {
-		return err
-	}
-
-	withEventHandler := WithTriggerAsyncEvent(p.GetHandlerWithInitTable(), func(event *Event) {
-		err = p.getEventHandler()(event.LastInsertId, event.RowsAffected)
-		if err != nil {
-			p.Log(sql, err)
-		}
-	})
-	_, _, err = withEventHandler.InsertWithLastId(sql)
+	_, _, err = p.InsertWithLastId()
 	return err
 }
+
+// InsertWithLastId 分表(分库)时按行路由分组,每组一条 insert 语句,lastInsertId 取最后一组,rowsAffected 累加
 func (p BatchInsertParam) InsertWithLastId() (lastInsertId uint64, rowsAffected int64, err error) {
-	sql, err := p.ToSQL()
+	if p._Table.isSharded() {
+		return p.shardedInsertWithLastId()
+	}
+	return p.insertWithLastId()
+}
+
+func (p BatchInsertParam) insertWithLastId() (lastInsertId uint64, rowsAffected int64, err error) {
+	sql, data, err := p.toSQL()
 	if err != nil {
 		return 0, 0, err
 	}
+	for _, rowData := range data {
+		err = p.GetTable().CheckForeignKeys(rowData)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
 	withEventHandler := WithTriggerAsyncEvent(p.GetHandlerWithInitTable(), func(event *Event) {
 		err = p.getEventHandler()(event.LastInsertId, event.RowsAffected)
 		if err != nil {
 			p.Log(sql, err)
 		}
 	})
-	return withEventHandler.InsertWithLastId(sql)
+	lastInsertId, rowsAffected, err = withEventHandler.InsertWithLastId(sql)
+	if err != nil {
+		return 0, 0, p.GetTable().TranslateError(err)
+	}
+	return lastInsertId, rowsAffected, nil
 }
 
 type DeleteParam struct {
@@ -633,25 +725,33 @@ func (p *DeleteParam) ApplyCustomFn(customFns ...CustomFnDeleteParam) *DeletePar
 	return p
 }
 
-func (p DeleteParam) ToSQL(fs Fields) (sql string, err error) {
+func (p DeleteParam) makeUpdateDataset(fs Fields) (ds *goqu.UpdateDataset, err error) {
 	tableConfig := p.GetTable()
 	fs = fs.Builder(p.context, SCENE_SQL_DELETE, tableConfig, p.customFieldsFns) // 使用复制变量,后续正对场景的舒适化处理不会影响原始变量
 	f, err := fs.DeletedAt()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	canUpdateFields := fs.GetByTags(Field_tag_CanWriteWhenDeleted)
 	canUpdateFields.Append(f)
 	data, err := canUpdateFields.Data(layer_order...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	where, err := fs.Where()
+	if err != nil {
+		return nil, err
+	}
+	ds = p.GetGoquDialect().Update(tableConfig.Name).Set(data).Where(where...)
+	return ds, nil
+}
+
+func (p DeleteParam) ToSQL(fs Fields) (sql string, err error) {
+	ds, err := p.makeUpdateDataset(fs)
 	if err != nil {
 		return "", err
 	}
-	ds := p.GetGoquDialect().Update(tableConfig.Name).Set(data).Where(where...)
 	sql, _, err = ds.ToSQL()
 	if err != nil {
 		err = errors.Wrap(err, "build delete sql error")
@@ -661,6 +761,10 @@ func (p DeleteParam) ToSQL(fs Fields) (sql string, err error) {
 	return sql, nil
 }
 func (p DeleteParam) Exec() (err error) {
+	if p._Table.isSharded() {
+		_, err = p.shardedDelete()
+		return err
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "DeleteParam.Exec",
@@ -694,10 +798,16 @@ func (p DeleteParam) exec(fs Fields) (err error) {
 		}
 	})
 	_, err = withEventHandler.ExecWithRowsAffected(sql)
-	return err
+	if err != nil {
+		return p.GetTable().TranslateError(err)
+	}
+	return nil
 }
 
 func (p DeleteParam) Delete() (rowsAffected int64, err error) {
+	if p._Table.isSharded() {
+		return p.shardedDelete()
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "DeleteParam.delete",
@@ -734,7 +844,10 @@ func (p DeleteParam) delete(fs Fields) (rowsAffected int64, err error) {
 		}
 	})
 	rowsAffected, err = withEventHandler.ExecWithRowsAffected(sql)
-	return rowsAffected, err
+	if err != nil {
+		return 0, p.GetTable().TranslateError(err)
+	}
+	return rowsAffected, nil
 }
 
 // deprecated use Delete instead
@@ -744,6 +857,7 @@ func (p DeleteParam) ExecWithRowsAffected() (rowsAffected int64, err error) {
 
 type UpdateParam struct {
 	mustExists           bool
+	onlyChanged          bool
 	_triggerUpdatedEvent EventUpdateTrigger
 	SQLParam[UpdateParam]
 }
@@ -779,35 +893,48 @@ func (p *UpdateParam) ApplyCustomFn(customFns ...CustomFnUpdateParam) *UpdatePar
 	return p
 }
 
-func (p UpdateParam) ToSQL(fs Fields) (sql string, err error) {
+func (p UpdateParam) makeUpdateDataset(fs Fields) (ds *goqu.UpdateDataset, data any, err error) {
 	tableConfig := p.GetTable()
 	fs = fs.Builder(p.context, SCENE_SQL_UPDATE, tableConfig, p.customFieldsFns) // 使用复制变量,后续针对场景的特殊化处理不会影响原始变量
-	data, err := fs.Data(layer_order...)
+	data, err = fs.Data(layer_order...)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
 	where, err := fs.Where()
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 	if len(where) == 0 {
 		err = errors.WithMessage(ErrEmptyWhere, "update must have where condition")
-		return "", err
+		return nil, nil, err
 	}
 	limit := fs.Limit()
 
-	ds := p.GetGoquDialect().Update(tableConfig.Name).Set(data).Where(where...).Order(fs.Order()...)
+	ds = p.GetGoquDialect().Update(tableConfig.Name).Set(data).Where(where...).Order(fs.Order()...)
 	if limit > 0 {
 		ds = ds.Limit(limit)
 	}
+	return ds, data, nil
+}
 
+func (p UpdateParam) ToSQL(fs Fields) (sql string, err error) {
+	sql, _, err = p.toSQL(fs)
+	return sql, err
+}
+
+// toSQL 同时返回更新数据,供执行前校验外键
+func (p UpdateParam) toSQL(fs Fields) (sql string, data any, err error) {
+	ds, data, err := p.makeUpdateDataset(fs)
+	if err != nil {
+		return "", nil, err
+	}
 	sql, _, err = ds.ToSQL()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	p.Log(sql)
-	return sql, nil
+	return sql, data, nil
 }
 
 func (p UpdateParam) Validate() (err error) {
@@ -830,11 +957,27 @@ func (p UpdateParam) ExecWithRowsAffected() (rowsAffected int64, err error) {
 }
 
 func (p UpdateParam) Update() (rowsAffected int64, err error) {
+	if p._Table.isSharded() {
+		return p.shardedUpdate()
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "UpdateParam.update",
 		Fn: func(ctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
-			rowsAffected, err = p.update(*fsRef)
+			fs := *fsRef
+			if p.onlyChanged {
+				var changedColumns []string
+				fs, changedColumns, err = p.onlyChangedFields(fs)
+				if err != nil {
+					return err
+				}
+				*fsRef = fsRef.Append(NewChangedColumns(changedColumns))
+				if len(changedColumns) == 0 { // 无变化,不执行更新
+					*fsRef = fsRef.Append(NewRowsAffected(0))
+					return ctx.Next(fsRef)
+				}
+			}
+			rowsAffected, err = p.update(fs)
 			if err != nil {
 				return err
 			}
@@ -856,7 +999,11 @@ func (p UpdateParam) Update() (rowsAffected int64, err error) {
 }
 
 func (p UpdateParam) update(fs Fields) (rowsAffected int64, err error) {
-	sql, err := p.ToSQL(fs)
+	sql, data, err := p.toSQL(fs)
+	if err != nil {
+		return 0, err
+	}
+	err = p.GetTable().CheckForeignKeys(data)
 	if err != nil {
 		return 0, err
 	}
@@ -885,7 +1032,10 @@ func (p UpdateParam) update(fs Fields) (rowsAffected int64, err error) {
 		}
 	})
 	rowsAffected, err = withEventHandler.ExecWithRowsAffected(sql)
-	return rowsAffected, err
+	if err != nil {
+		return 0, p.GetTable().TranslateError(err)
+	}
+	return rowsAffected, nil
 }
 
 // Deprecated :已废弃,请使用p.WithMustExists(true).Update()(UpdateMustExists 这个名字很难想起来,所以改为配置模式，另外也减少重复代码)
@@ -921,6 +1071,8 @@ func GetCacheDuration(ctx context.Context) time.Duration {
 
 type FirstParam struct {
 	builderFns SelectBuilderFns
+	preloads   []string
+	projection projection
 	SQLParam[FirstParam]
 }
 
@@ -943,6 +1095,12 @@ func (p *FirstParam) WithBuilderFns(builderFns ...SelectBuilderFn) *FirstParam {
 	return p
 }
 
+// Preload 查询后按表关系(TableConfig.Relations)名称加载关联数据,支持 "items.product" 嵌套加载
+func (p *FirstParam) Preload(relationNames ...string) *FirstParam {
+	p.preloads = append(p.preloads, relationNames...)
+	return p
+}
+
 type CustomFnFirstParam = CustomFn[FirstParam]
 type CustomFnFirstParams = CustomFns[FirstParam]
 
@@ -960,7 +1118,12 @@ func (p FirstParam) ToSQL(fs Fields) (sql string, err error) {
 		err = errors.Wrap(err, errWithMsg)
 		return "", err
 	}
-	ds := p.GetGoquDialect().Select(p.getSelectColumns(tableConfig, fs)...).
+	selec, err := p.getProjectionSelectColumns(tableConfig, fs, p.projection, p.preloads)
+	if err != nil {
+		err = errors.WithMessage(err, errWithMsg)
+		return "", err
+	}
+	ds := p.GetGoquDialect().Select(selec...).
 		From(tableConfig.AliasOrTableExpr()).
 		Where(where...).
 		Order(fs.Order()...).
@@ -976,6 +1139,9 @@ func (p FirstParam) ToSQL(fs Fields) (sql string, err error) {
 }
 
 func (p FirstParam) First(result any) (exists bool, err error) {
+	if p._Table.isSharded() {
+		return p.shardedFirst(result)
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "FirstParam.First",
@@ -1014,7 +1180,14 @@ func (p FirstParam) first(fs Fields, result any) (exists bool, err error) {
 		handler = _WithCache(handler)
 	}
 	exists, err = handler.First(p.context, sql, result)
-	return exists, err
+	if err != nil || !exists {
+		return exists, err
+	}
+	err = preloadRelations(p.context, p.GetTable(), p.GetTable().GetHandler(), result, p.preloads)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
 }
 
 func (p FirstParam) FirstMustExists(result any) (err error) {
@@ -1042,6 +1215,8 @@ func (fns SelectBuilderFns) Apply(ds *goqu.SelectDataset) *goqu.SelectDataset {
 
 type ListParam struct {
 	builderFns SelectBuilderFns
+	preloads   []string
+	projection projection
 	SQLParam[ListParam]
 }
 
@@ -1057,6 +1232,12 @@ func (p *ListParam) WithBuilderFns(builderFns ...SelectBuilderFn) *ListParam {
 	return p
 }
 
+// Preload 查询后按表关系(TableConfig.Relations)名称加载关联数据,每个关系一次 IN 查询,避免 N+1 查询;支持 "items.product" 嵌套加载
+func (p *ListParam) Preload(relationNames ...string) *ListParam {
+	p.preloads = append(p.preloads, relationNames...)
+	return p
+}
+
 type CustomFnListParam = CustomFn[ListParam]
 type CustomFnListParams = CustomFns[ListParam]
 
@@ -1082,7 +1263,11 @@ func (p ListParam) makeSelectDataset(fs Fields) (ds *goqu.SelectDataset, err err
 	pageIndex, pageSize := fs.Pagination()
 	ofsset := max(pageIndex*pageSize, 0)
 
-	selec := p.getSelectColumns(tableConfig, fs)
+	selec, err := p.getProjectionSelectColumns(tableConfig, fs, p.projection, p.preloads)
+	if err != nil {
+		err = errors.WithMessage(err, errWithMsg)
+		return nil, err
+	}
 	order := fs.Order()
 	if len(order) == 0 { // 没有排序字段,则默认按主键降序排列
 		table := p.GetTable()
@@ -1128,6 +1313,9 @@ func (p ListParam) Query(result any) (err error) {
 	return p.List(result)
 }
 func (p ListParam) List(result any) (err error) {
+	if p._Table.isSharded() {
+		return p.shardedList(result)
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "ListParam.list",
@@ -1165,6 +1353,10 @@ func (p ListParam) list(fs Fields, result any) (err error) {
 	if err != nil {
 		return err
 	}
+	err = preloadRelations(p.context, p.GetTable(), p.GetTable().GetHandler(), result, p.preloads)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -1243,13 +1435,7 @@ func (p ExistsParam) ToSQL(fs Fields) (sql string, err error) {
 		return "", err
 	}
 
-	ds := p.GetGoquDialect().
-		From(tableConfig.AliasOrTableExpr()).
-		Where(where...).
-		Limit(1)
-	ds = p.builderFns.Apply(ds)
-	ds = ds.Select(goqu.L("1").As("exists")) // 确保不会被 p.builderFns.Apply 覆盖
-
+	ds := p.makeSelectDataset(where)
 	sql, _, err = ds.ToSQL()
 	if err != nil {
 		err = errors.WithMessage(err, errWithMsg)
@@ -1264,14 +1450,27 @@ func (p ExistsParam) ToSQL(fs Fields) (sql string, err error) {
 	return sql, nil
 }
 
+func (p ExistsParam) makeSelectDataset(where Expressions) (ds *goqu.SelectDataset) {
+	ds = p.GetGoquDialect().
+		From(p.GetTable().AliasOrTableExpr()).
+		Where(where...).
+		Limit(1)
+	ds = p.builderFns.Apply(ds)
+	ds = ds.Select(goqu.L("1").As("exists")) // 确保不会被 p.builderFns.Apply 覆盖
+	return ds
+}
+
 func (p ExistsParam) Exists() (exists bool, err error) {
+	if p._Table.isSharded() {
+		return p.shardedExists()
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "ExistsParam.Exists",
 		Fn: func(ctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
 			exists, err = p.exists(*fsRef)
 			if err != nil {
-				return nil
+				return err
 			}
 			*fsRef = fsRef.Append(NewExists(exists))
 			err = ctx.Next(fsRef)
@@ -1398,6 +1597,9 @@ func (p TotalParam) ToSQL(fs Fields) (sql string, err error) {
 }
 
 func (p TotalParam) Count() (total int64, err error) {
+	if p._Table.isSharded() {
+		return p.shardedCount()
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "TotalParam.Count",
@@ -1434,6 +1636,7 @@ func (p TotalParam) count(fs Fields) (total int64, err error) {
 
 type PaginationParam struct {
 	builderFns SelectBuilderFns
+	projection projection
 	SQLParam[PaginationParam]
 	countColumns []any
 }
@@ -1473,7 +1676,7 @@ func (p PaginationParam) ToSQL(fs Fields) (totalSql string, listSql string, err
 	if err != nil {
 		return "", "", err
 	}
-	listSql, err = NewListBuilder(table).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...).WithBuilderFns(p.builderFns...).WithResultDest(p.resultDst).ToSQL(fs)
+	listSql, err = NewListBuilder(table).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...).WithBuilderFns(p.builderFns...).WithProjection(p.projection...).WithResultDest(p.resultDst).ToSQL(fs)
 	if err != nil {
 		return "", "", err
 	}
@@ -1531,8 +1734,9 @@ func (p PaginationParam) Pagination(result any) (total int64, err error) {
 
 func (p PaginationParam) pagination(fs Fields, result any) (count int64, err error) {
 	p.resultDst = result
-	isShardedTable := p.GetTable().isShardedTable()
+	isShardedTable := p.GetTable().isSharded()
 	if isShardedTable {
+		p.modelMiddlewarePool = p.modelMiddlewarePool.remove("PaginationParam.pagination") // 分表各自执行中间件,避免递归进入当前中间件
 		shardedTablePaginationBuilder := NewShardedTablePaginationBuilder(p)
 		count, err = shardedTablePaginationBuilder.Pagination(result)
 		if err != nil {
@@ -1664,6 +1868,13 @@ func (p SetParam) ToSQL(fsRef Fields) (existsSql string, insertSql string, updat
 }
 
 func (p SetParam) Set() (isNotExits bool, lastInsertId uint64, rowsAffected int64, err error) {
+	if p._Table.isSharded() {
+		table, err := p._Table.routeShardedTable(p._Fields)
+		if err != nil {
+			return false, 0, 0, err
+		}
+		p._Table = table
+	}
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
 	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
 		Name: "SetParam.Set",
@@ -1736,16 +1947,20 @@ func (p SetParam) set(fs Fields) (isNotExits bool, lastInsertId uint64, rowsAffe
 		}
 	}
 	isNotExits = !exists
+	err = p.checkForeignKeys(fs, exists)
+	if err != nil {
+		return isNotExits, 0, 0, err
+	}
 	switch p.setPolicy {
 	case SetPolicy_only_Insert: // 只新增说明使用最早数据
 		if !exists {
 			lastInsertId, rowsAffected, err = withInsertEventHandler.InsertWithLastId(insertSql)
-			return isNotExits, lastInsertId, rowsAffected, err
+			return isNotExits, lastInsertId, rowsAffected, p.GetTable().TranslateError(err)
 		}
 	case SetPolicy_only_Update: // 只更新说明不存在时不处理
 		if exists {
 			rowsAffected, err = withUpdateEventHandler.ExecWithRowsAffected(updateSql)
-			return isNotExits, lastInsertId, rowsAffected, err
+			return isNotExits, lastInsertId, rowsAffected, p.GetTable().TranslateError(err)
 		}
 	case SetPolicy_Delete_and_insert:
 		if exists {
@@ -1755,7 +1970,7 @@ func (p SetParam) set(fs Fields) (isNotExits bool, lastInsertId uint64, rowsAffe
 			}
 		}
 		lastInsertId, rowsAffected, err = withInsertEventHandler.InsertWithLastId(insertSql)
-		return isNotExits, lastInsertId, rowsAffected, err
+		return isNotExits, lastInsertId, rowsAffected, p.GetTable().TranslateError(err)
 	default: // 默认执行 SetPolicy_Insert_or_Update 策略
 		if exists {
 			rowsAffected, err = withUpdateEventHandler.ExecWithRowsAffected(updateSql)
@@ -1763,7 +1978,28 @@ func (p SetParam) set(fs Fields) (isNotExits bool, lastInsertId uint64, rowsAffe
 			lastInsertId, rowsAffected, err = withInsertEventHandler.InsertWithLastId(insertSql)
 		}
 	}
-	return isNotExits, lastInsertId, rowsAffected, err
+	return isNotExits, lastInsertId, rowsAffected, p.GetTable().TranslateError(err)
+}
+
+// checkForeignKeys 按即将执行的新增或更新数据校验外键
+func (p SetParam) checkForeignKeys(fs Fields, exists bool) (err error) {
+	table := p.GetTable()
+	if len(table.ForeignKeys.GetCheckExists()) == 0 {
+		return nil
+	}
+	var data any
+	switch {
+	case !exists && p.setPolicy != SetPolicy_only_Update, p.setPolicy == SetPolicy_Delete_and_insert:
+		_, data, err = NewInsertBuilder(table).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...).toSQL(fs)
+	case exists && p.setPolicy != SetPolicy_only_Insert:
+		_, data, err = NewUpdateBuilder(table).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...).toSQL(fs)
+	default: // 不写入数据
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return table.CheckForeignKeys(data)
 }
 
 func MergeData(dataFns ...func() (any, error)) (map[string]any, error) {
@@ -1869,7 +2105,7 @@ func (p *SQLParam[T]) GetGoquDialect() goqu.DialectWrapper {
 	if p._Table._handler != nil {
 		dialect = p._Table.GetHandler().GetDialector()
 	}
-	return goqu.Dialect(dialect)
+	return Driver(dialect).GoquDialect()
 }
 
 func (p *SQLParam[T]) WithHandlerMiddleware(middlewares ...HandlerMiddleware) *T {
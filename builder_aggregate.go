@@ -0,0 +1,299 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+)
+
+// AggregateFn 聚合函数
+type AggregateFn string
+
+const (
+	AggregateFn_sum           AggregateFn = "sum"
+	AggregateFn_avg           AggregateFn = "avg"
+	AggregateFn_min           AggregateFn = "min"
+	AggregateFn_max           AggregateFn = "max"
+	AggregateFn_count         AggregateFn = "count"
+	AggregateFn_countDistinct AggregateFn = "countDistinct"
+)
+
+func (fn AggregateFn) tag() string {
+	return fmt.Sprintf("%s%s", field_tag_aggregate_prefix, fn)
+}
+
+func (fn AggregateFn) Expression(column string) (expression exp.SQLFunctionExpression, err error) {
+	col := goqu.I(column)
+	switch fn {
+	case AggregateFn_sum:
+		return goqu.SUM(col), nil
+	case AggregateFn_avg:
+		return goqu.AVG(col), nil
+	case AggregateFn_min:
+		return goqu.MIN(col), nil
+	case AggregateFn_max:
+		return goqu.MAX(col), nil
+	case AggregateFn_count:
+		return goqu.COUNT(col), nil
+	case AggregateFn_countDistinct:
+		return goqu.COUNT(goqu.DISTINCT(col)), nil
+	}
+	err = errors.Errorf("unsupported aggregate function:%s", fn)
+	return nil, err
+}
+
+// AggregateParam 聚合查询,Field.SetGroupBy 标记分组列,Field.SetAggregate 标记度量列,其余字段作为where条件
+type AggregateParam struct {
+	builderFns SelectBuilderFns
+	SQLParam[AggregateParam]
+}
+
+func NewAggregateBuilder(tableConfig TableConfig) *AggregateParam {
+	p := &AggregateParam{}
+	p.SQLParam = NewSQLParam(p, tableConfig)
+	return p
+}
+
+func (p *AggregateParam) WithCacheDuration(duration time.Duration) *AggregateParam {
+	p.context = WithCacheDuration(p.context, duration)
+	return p
+}
+
+func (p *AggregateParam) WithBuilderFns(builderFns ...SelectBuilderFn) *AggregateParam {
+	if len(p.builderFns) == 0 {
+		p.builderFns = SelectBuilderFns{}
+	}
+	p.builderFns = append(p.builderFns, builderFns...)
+	return p
+}
+
+type CustomFnAggregateParam = CustomFn[AggregateParam]
+type CustomFnAggregateParams = CustomFns[AggregateParam]
+
+func (p *AggregateParam) ApplyCustomFn(customFns ...CustomFnAggregateParam) *AggregateParam {
+	p = CustomFns[AggregateParam](customFns).Apply(p)
+	return p
+}
+
+// makeSelectDataset 生成不含分页的聚合查询
+func (p AggregateParam) makeSelectDataset(fs Fields) (ds *goqu.SelectDataset, err error) {
+	tableConfig := p.GetTable()
+	fs = fs.Builder(p.context, SCENE_SQL_SELECT, tableConfig, p.customFieldsFns) // 使用复制变量,后续正对场景的舒适化处理不会影响原始变量
+	errWithMsg := fmt.Sprintf("AggregateParam.ToSQL(),table:%s", tableConfig.Name)
+	selec := make([]any, 0)
+	groupBy := make([]any, 0)
+	where := make(Expressions, 0)
+	having := make(Expressions, 0)
+	for _, f := range fs {
+		columnName := f.DBColumnName().FullName()
+		if f.HastTag(Field_tag_groupBy) {
+			selec = append(selec, goqu.I(columnName).As(f.Name))
+			groupBy = append(groupBy, goqu.I(columnName))
+		}
+		aggregateFn, isMeasure := f.GetAggregate()
+		if !isMeasure {
+			subWhere, err := f.Where(fs...)
+			if err != nil {
+				err = errors.WithMessage(err, errWithMsg)
+				return nil, err
+			}
+			where = append(where, subWhere...)
+			continue
+		}
+		expression, err := aggregateFn.Expression(columnName)
+		if err != nil {
+			err = errors.WithMessage(err, errWithMsg)
+			return nil, err
+		}
+		selec = append(selec, expression.As(f.Name))
+		subHaving, err := f.havingExpressions(fs...)
+		if err != nil {
+			err = errors.WithMessage(err, errWithMsg)
+			return nil, err
+		}
+		having = append(having, subHaving...)
+	}
+	if len(selec) == 0 {
+		err = errors.Errorf("%s required group by or aggregate field", errWithMsg)
+		return nil, err
+	}
+
+	ds = p.GetGoquDialect().Select(selec...).
+		From(tableConfig.AliasOrTableExpr()).
+		Where(where...).
+		GroupBy(groupBy...)
+	if len(having) > 0 {
+		ds = ds.Having(having...)
+	}
+	ds = ds.Order(aggregateOrder(fs)...)
+	ds = p.builderFns.Apply(ds)
+	return ds, nil
+}
+
+// havingExpressions 度量列的where条件作用于聚合结果,使用别名引用
+func (f Field) havingExpressions(fs ...*Field) (expressions Expressions, err error) {
+	val, err := f.WhereData(Layer_where, fs...)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, nil
+	}
+	if ex, ok := TryParseExpressions(f.Name, val); ok {
+		return ex, nil
+	}
+	return ConcatExpression(goqu.Ex{f.Name: val}), nil
+}
+
+// aggregateOrder 度量列的排序使用别名引用,方向与字段排序函数一致
+func aggregateOrder(fs Fields) (orderedExpressions []exp.OrderedExpression) {
+	orderedExpressions = make([]exp.OrderedExpression, 0)
+	fs = fs.sortByOrderField()
+	for _, f := range fs {
+		subExprs := f.Order(fs...)
+		if _, isMeasure := f.GetAggregate(); !isMeasure {
+			orderedExpressions = append(orderedExpressions, subExprs...)
+			continue
+		}
+		for _, orderedExpression := range subExprs {
+			if orderedExpression.IsAsc() {
+				orderedExpressions = append(orderedExpressions, goqu.I(f.Name).Asc())
+				continue
+			}
+			orderedExpressions = append(orderedExpressions, goqu.I(f.Name).Desc())
+		}
+	}
+	return orderedExpressions
+}
+
+func (p AggregateParam) ToSQL(fs Fields) (sql string, err error) {
+	ds, err := p.makeSelectDataset(fs)
+	if err != nil {
+		return "", err
+	}
+	pageIndex, pageSize := fs.Pagination()
+	if pageSize > 0 {
+		ds = ds.Offset(pageIndex * pageSize).Limit(pageSize)
+	}
+	sql, _, err = ds.ToSQL()
+	if err != nil {
+		err = errors.WithMessagef(err, "AggregateParam.ToSQL(),table:%s", p.GetTable().Name)
+		return "", err
+	}
+	p.Log(sql)
+	return sql, nil
+}
+
+// TotalSQL 统计分组数量
+func (p AggregateParam) TotalSQL(fs Fields) (sql string, err error) {
+	ds, err := p.makeSelectDataset(fs)
+	if err != nil {
+		return "", err
+	}
+	ds = p.GetGoquDialect().From(ds.ClearOrder().As("sub")).Select(goqu.COUNT(goqu.Star()).As("count"))
+	sql, _, err = ds.ToSQL()
+	if err != nil {
+		err = errors.WithMessagef(err, "AggregateParam.TotalSQL(),table:%s", p.GetTable().Name)
+		return "", err
+	}
+	p.Log(sql)
+	return sql, nil
+}
+
+func (p AggregateParam) getHandler() (handler Handler) {
+	handler = p.GetHandlerWithInitTable()
+	cacheDuration := GetCacheDuration(p.context)
+	if cacheDuration > 0 {
+		handler = _WithCache(handler)
+	}
+	return handler
+}
+
+// Aggregate 执行聚合查询,结果通过 Scan 写入result(结构体切片/map切片等)
+func (p AggregateParam) Aggregate(result any) (err error) {
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "AggregateParam.aggregate",
+		Fn: func(ctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			err = p.aggregate(*fsRef, result)
+			if err != nil {
+				return err
+			}
+			err = ctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p AggregateParam) aggregate(fs Fields, result any) (err error) {
+	sql, err := p.ToSQL(fs)
+	if err != nil {
+		return err
+	}
+	return p.getHandler().Query(p.context, sql, result)
+}
+
+// Pagination 分页聚合查询,total 为分组数量
+func (p AggregateParam) Pagination(result any) (total int64, err error) {
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "AggregateParam.pagination",
+		Fn: func(ctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			total, err = p.pagination(*fsRef, result)
+			if err != nil {
+				return err
+			}
+			*fsRef = fsRef.Append(NewTotal(total))
+			err = ctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (p AggregateParam) pagination(fs Fields, result any) (total int64, err error) {
+	index, size := fs.Pagination()
+	if index == 0 && size == 0 {
+		err = ErrPaginationSizeRequired
+		return 0, err
+	}
+	totalSql, err := p.TotalSQL(fs)
+	if err != nil {
+		return 0, err
+	}
+	handler := p.getHandler()
+	total, err = handler.Count(totalSql)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	sql, err := p.ToSQL(fs)
+	if err != nil {
+		return 0, err
+	}
+	err = handler.Query(p.context, sql, result)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,46 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func NewAggregateUserId(userId int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(userId, "userId", "用户ID", 0)
+}
+
+func NewAggregateAmount(amount int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(amount, "amount", "金额", 0)
+}
+
+var aggregateOrderTable = sqlbuilder.NewTableConfig("aggregate_order").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("user_id", sqlbuilder.GetField(NewAggregateUserId)),
+	sqlbuilder.NewColumn("amount", sqlbuilder.GetField(NewAggregateAmount)),
+).AddIndexs(fkPrimaryIndex)
+
+func TestAggregate(t *testing.T) {
+	db := sqlbuildertest.New(t, aggregateOrderTable)
+	db.InsertFixtures(sqlbuildertest.Fixtures{"aggregate_order": {
+		{"id": 1, "user_id": 1, "amount": 10}, {"id": 2, "user_id": 1, "amount": 20}, {"id": 3, "user_id": 2, "amount": 5},
+		{"id": 4, "user_id": 3, "amount": 100}, {"id": 5, "user_id": 3, "amount": 1},
+	}})
+	table := db.Table("aggregate_order")
+	type row struct {
+		UserId int `db:"userId"`
+		Amount int `db:"amount"`
+	}
+	result := make([]row, 0)
+	total, err := sqlbuilder.NewAggregateBuilder(table).AppendFields(
+		NewAggregateUserId(0).SetGroupBy(),
+		NewAggregateAmount(10).SetAggregate(sqlbuilder.AggregateFn_sum).AppendWhereFn(sqlbuilder.ValueFnGte).WithOrderFn(0, sqlbuilder.OrderFnDesc),
+		NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex),
+		NewPageSize(1).SetTag(sqlbuilder.Field_tag_pageSize),
+	).Pagination(&result)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Equal(t, []row{{UserId: 3, Amount: 101}}, result)
+}
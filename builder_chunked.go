@@ -0,0 +1,292 @@
+package sqlbuilder
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pkg/errors"
+)
+
+// ChunkedProgress 分批执行进度
+type ChunkedProgress struct {
+	Batch             int   // 当前批次,从1开始
+	RowsAffected      int64 // 当前批次影响行数
+	TotalRowsAffected int64 // 累计影响行数
+	LastPrimaryKey    any   // 当前批次最后一条记录的主键
+}
+
+// ChunkedProgressFn 进度回调,返回错误时终止后续批次
+type ChunkedProgressFn func(progress ChunkedProgress) (err error)
+
+// chunkedOption 分批执行配置,每批先按主键查询出本批次主键,再按主键更新,避免长时间锁表
+type chunkedOption struct {
+	size       int
+	interval   time.Duration
+	progressFn ChunkedProgressFn
+}
+
+// ChunkedUpdateParam 分批更新,用于大批量数据修复
+type ChunkedUpdateParam struct {
+	UpdateParam
+	chunkedOption
+}
+
+// Chunked 按主键分批更新,每批最多size条
+func (p UpdateParam) Chunked(size int) *ChunkedUpdateParam {
+	return &ChunkedUpdateParam{UpdateParam: p, chunkedOption: chunkedOption{size: size}}
+}
+
+// WithInterval 批次间休眠时长
+func (p *ChunkedUpdateParam) WithInterval(interval time.Duration) *ChunkedUpdateParam {
+	p.interval = interval
+	return p
+}
+
+func (p *ChunkedUpdateParam) WithProgressFn(progressFn ChunkedProgressFn) *ChunkedUpdateParam {
+	p.progressFn = progressFn
+	return p
+}
+
+func (p ChunkedUpdateParam) Update(ctx context.Context) (rowsAffected int64, err error) {
+	if p._Table.isSharded() {
+		return p.shardedUpdate(ctx)
+	}
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "ChunkedUpdateParam.update",
+		Fn: func(mctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			ds, data, err := p.makeUpdateDataset(*fsRef)
+			if err != nil {
+				return err
+			}
+			err = p.GetTable().CheckForeignKeys(data)
+			if err != nil {
+				return err
+			}
+			rowsAffected, err = p.chunkedOption.run(ctx, p.GetTable(), p.GetHandlerWithInitTable(), p.GetGoquDialect(), ds, func(sql string) (rowsAffected int64, err error) {
+				p.Log(sql)
+				withEventHandler := WithTriggerAsyncEvent(p.GetHandlerWithInitTable(), func(event *Event) {
+					eventErr := p.getEventHandler()(event.RowsAffected) // 异步执行,不能复用外层err
+					if eventErr != nil {
+						p.Log(sql, eventErr)
+					}
+				})
+				rowsAffected, err = withEventHandler.ExecWithRowsAffected(sql)
+				if err != nil {
+					return 0, p.GetTable().TranslateError(err)
+				}
+				return rowsAffected, nil
+			})
+			if err != nil {
+				return err
+			}
+			*fsRef = fsRef.Append(
+				NewRowsAffected(rowsAffected),
+			)
+			err = mctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		return rowsAffected, err
+	}
+	return rowsAffected, nil
+}
+
+// shardedUpdate 分批更新路由到分表,未指定分表键时依次分批更新所有匹配分表,进度批次按分表重新计数
+func (p ChunkedUpdateParam) shardedUpdate(ctx context.Context) (rowsAffected int64, err error) {
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return 0, err
+	}
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		subRowsAffected, err := shardedP.Update(ctx)
+		if err != nil {
+			return rowsAffected, err
+		}
+		rowsAffected += subRowsAffected
+	}
+	return rowsAffected, nil
+}
+
+// ChunkedDeleteParam 分批删除(软删除),用于大批量数据清理
+type ChunkedDeleteParam struct {
+	DeleteParam
+	chunkedOption
+}
+
+// Chunked 按主键分批删除,每批最多size条
+func (p DeleteParam) Chunked(size int) *ChunkedDeleteParam {
+	return &ChunkedDeleteParam{DeleteParam: p, chunkedOption: chunkedOption{size: size}}
+}
+
+// WithInterval 批次间休眠时长
+func (p *ChunkedDeleteParam) WithInterval(interval time.Duration) *ChunkedDeleteParam {
+	p.interval = interval
+	return p
+}
+
+func (p *ChunkedDeleteParam) WithProgressFn(progressFn ChunkedProgressFn) *ChunkedDeleteParam {
+	p.progressFn = progressFn
+	return p
+}
+
+func (p ChunkedDeleteParam) Delete(ctx context.Context) (rowsAffected int64, err error) {
+	if p._Table.isSharded() {
+		return p.shardedDelete(ctx)
+	}
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "ChunkedDeleteParam.delete",
+		Fn: func(mctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			ds, err := p.makeUpdateDataset(*fsRef)
+			if err != nil {
+				return err
+			}
+			rowsAffected, err = p.chunkedOption.run(ctx, p.GetTable(), p.GetHandlerWithInitTable(), p.GetGoquDialect(), ds, func(sql string) (rowsAffected int64, err error) {
+				p.Log(sql)
+				withEventHandler := WithTriggerAsyncEvent(p.GetHandlerWithInitTable(), func(event *Event) {
+					eventErr := p.getEventHandler()(event.RowsAffected) // 异步执行,不能复用外层err
+					if eventErr != nil {
+						p.Log(sql, eventErr)
+					}
+				})
+				rowsAffected, err = withEventHandler.ExecWithRowsAffected(sql)
+				if err != nil {
+					return 0, p.GetTable().TranslateError(err)
+				}
+				return rowsAffected, nil
+			})
+			if err != nil {
+				return err
+			}
+			*fsRef = fsRef.Append(
+				NewRowsAffected(rowsAffected),
+			)
+			err = mctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		return rowsAffected, err
+	}
+	return rowsAffected, nil
+}
+
+// shardedDelete 同 ChunkedUpdateParam.shardedUpdate
+func (p ChunkedDeleteParam) shardedDelete(ctx context.Context) (rowsAffected int64, err error) {
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return 0, err
+	}
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		subRowsAffected, err := shardedP.Delete(ctx)
+		if err != nil {
+			return rowsAffected, err
+		}
+		rowsAffected += subRowsAffected
+	}
+	return rowsAffected, nil
+}
+
+// run 循环执行: 按主键升序查询本批次主键(where 原条件 and pk>last limit size),再执行 where 原条件 and pk in(本批次主键),直到没有数据
+func (o chunkedOption) run(ctx context.Context, table TableConfig, handler Handler, dialect goqu.DialectWrapper, ds *goqu.UpdateDataset, execFn func(sql string) (rowsAffected int64, err error)) (totalRowsAffected int64, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if o.size <= 0 {
+		err = errors.Errorf("chunked size must be greater than 0,got:%d", o.size)
+		return 0, err
+	}
+	primary, exists := table.Indexs.GetPrimary()
+	if !exists {
+		err = errors.Errorf("chunked table(%s) primary key not found", table.Name)
+		return 0, err
+	}
+	columnNames := primary.ColumnNames(table)
+	if len(columnNames) != 1 {
+		err = errors.Errorf("chunked table(%s) required single column primary key,got:%v", table.Name, columnNames)
+		return 0, err
+	}
+	pk := goqu.I(fmt.Sprintf("%s.%s", table.Name, columnNames[0]))
+	where := ds.GetClauses().Where()
+	ds = ds.ClearOrder().ClearLimit()
+
+	var last any
+	for batch := 1; ; batch++ {
+		if err = ctx.Err(); err != nil {
+			return totalRowsAffected, err
+		}
+		selectDs := dialect.From(table.Name).Select(pk).Order(pk.Asc()).Limit(uint(o.size))
+		if where != nil {
+			selectDs = selectDs.Where(where)
+		}
+		if last != nil {
+			selectDs = selectDs.Where(pk.Gt(last))
+		}
+		selectSql, _, err := selectDs.ToSQL()
+		if err != nil {
+			return totalRowsAffected, err
+		}
+		ids := make([]any, 0, o.size)
+		_, err = queryRows(ctx, handler, selectSql, func(rows Rows) (err error) {
+			var id any
+			err = rows.Scan(&id)
+			if err != nil {
+				return err
+			}
+			if b, ok := id.([]byte); ok {
+				id = string(b)
+			}
+			ids = append(ids, id)
+			return nil
+		})
+		if err != nil {
+			return totalRowsAffected, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		last = ids[len(ids)-1]
+		sql, _, err := ds.Where(pk.In(ids)).ToSQL()
+		if err != nil {
+			return totalRowsAffected, err
+		}
+		rowsAffected, err := execFn(sql)
+		if err != nil {
+			return totalRowsAffected, err
+		}
+		totalRowsAffected += rowsAffected
+		if o.progressFn != nil {
+			err = o.progressFn(ChunkedProgress{Batch: batch, RowsAffected: rowsAffected, TotalRowsAffected: totalRowsAffected, LastPrimaryKey: last})
+			if err != nil {
+				return totalRowsAffected, err
+			}
+		}
+		if len(ids) < o.size {
+			break
+		}
+		if o.interval > 0 {
+			select {
+			case <-ctx.Done():
+				return totalRowsAffected, ctx.Err()
+			case <-time.After(o.interval):
+			}
+		}
+	}
+	return totalRowsAffected, nil
+}
@@ -0,0 +1,81 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func TestChunkedUpdate(t *testing.T) {
+	table := newIterateTable(t, 7)
+	batches := make([]int64, 0)
+	rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).AppendFields(
+		NewIterateUserName("renamed"),
+		NewIterateUserId(2).ShieldUpdate(true).AppendWhereFn(sqlbuilder.ValueFnGte),
+	).Chunked(2).WithProgressFn(func(progress sqlbuilder.ChunkedProgress) (err error) {
+		batches = append(batches, progress.RowsAffected)
+		return nil
+	}).Update(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 6, rowsAffected)
+	require.Equal(t, []int64{2, 2, 2}, batches)
+
+	names := make([]string, 0)
+	err = sqlbuilder.Iterate(context.Background(), sqlbuilder.NewListBuilder(table), func(row iterateUser) (err error) {
+		names = append(names, row.Name)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user1", "renamed", "renamed", "renamed", "renamed", "renamed", "renamed"}, names)
+}
+
+var chunkedUserTable = sqlbuilder.NewTableConfig("chunked_user").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+	sqlbuilder.NewColumn("deleted_at", sqlbuilder.GetField(NewFkDeletedAt)),
+).AddIndexs(fkPrimaryIndex, sqlbuilder.Index{
+	Unique: true,
+	ColumnNames: func(table sqlbuilder.TableConfig) (columnNames []string) {
+		return []string{"name"}
+	},
+})
+
+func newChunkedUserTable(t *testing.T) sqlbuilder.TableConfig {
+	db := sqlbuildertest.New(t, chunkedUserTable)
+	rows := make([]map[string]any, 0)
+	for i := 1; i <= 5; i++ {
+		rows = append(rows, map[string]any{"id": i, "name": fmt.Sprintf("user%d", i), "deleted_at": ""})
+	}
+	db.InsertFixtures(sqlbuildertest.Fixtures{"chunked_user": rows})
+	return db.Table("chunked_user")
+}
+
+func TestChunkedDelete(t *testing.T) {
+	table := newChunkedUserTable(t)
+	batches := make([]int64, 0)
+	rowsAffected, err := sqlbuilder.NewDeleteBuilder(table).AppendFields(
+		NewFkDeletedAt("2024-01-01 00:00:00").SetFieldName(sqlbuilder.Field_name_deletedAt),
+		NewIterateUserId(2).AppendWhereFn(sqlbuilder.ValueFnGte),
+	).Chunked(2).WithProgressFn(func(progress sqlbuilder.ChunkedProgress) (err error) {
+		batches = append(batches, progress.RowsAffected)
+		return nil
+	}).Delete(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 4, rowsAffected)
+	require.Equal(t, []int64{2, 2}, batches)
+	sqlbuildertest.AssertRowCount(t, table, 1, map[string]any{"deleted_at": ""})
+	sqlbuildertest.AssertRowExists(t, table, map[string]any{"id": 1, "deleted_at": ""})
+}
+
+func TestChunkedTranslateError(t *testing.T) {
+	table := newChunkedUserTable(t)
+	_, err := sqlbuilder.NewUpdateBuilder(table).AppendFields(
+		NewIterateUserName("same"),
+		NewIterateUserId(1).ShieldUpdate(true).AppendWhereFn(sqlbuilder.ValueFnGte),
+	).Chunked(2).Update(context.Background())
+	require.ErrorIs(t, err, sqlbuilder.ErrDuplicateKey)
+}
@@ -0,0 +1,167 @@
+package sqlbuilder
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// ExplainPlan 执行计划中的一行,mysql 取 EXPLAIN 结果,sqlite 取 EXPLAIN QUERY PLAN 结果
+type ExplainPlan struct {
+	Table         string            `json:"table"`
+	Type          string            `json:"type"`   // mysql 访问类型,如 ALL、ref、const
+	Key           string            `json:"key"`    // mysql 实际使用的索引
+	Rows          int64             `json:"rows"`   // mysql 预估扫描行数
+	Extra         string            `json:"extra"`  // mysql Extra 列
+	Detail        string            `json:"detail"` // sqlite 执行计划描述,如 SCAN user、SEARCH user USING INDEX ...
+	FullTableScan bool              `json:"fullTableScan"`
+	Raw           map[string]string `json:"raw"` // 原始列
+}
+
+type ExplainPlans []ExplainPlan
+
+// FullTableScans 返回全表扫描的执行计划
+func (plans ExplainPlans) FullTableScans() (scans ExplainPlans) {
+	for _, plan := range plans {
+		if plan.FullTableScan {
+			scans = append(scans, plan)
+		}
+	}
+	return scans
+}
+
+func (plans ExplainPlans) HasFullTableScan() bool {
+	return len(plans.FullTableScans()) > 0
+}
+
+// Explain 在句柄对应的数据库上分析查询sql的执行计划,仅支持 mysql、sqlite
+func Explain(handler Handler, selectSql string) (plans ExplainPlans, err error) {
+	driver := Driver(handler.GetDialector())
+	var explainSql string
+	switch {
+	case driver.IsSame(Driver_mysql):
+		explainSql = "EXPLAIN " + selectSql
+	case driver.IsSame(Driver_sqlite3), driver.IsSame(_Driver_sqlite):
+		explainSql = "EXPLAIN QUERY PLAN " + selectSql
+	default:
+		err = errors.Errorf("explain unsupported driver:%s", driver)
+		return nil, err
+	}
+	db := handler.GetSqlDBHandler()()
+	if db == nil {
+		err = errors.Errorf("explain required database connection,driver:%s", driver)
+		return nil, err
+	}
+	rows, err := db.Query(explainSql)
+	if err != nil {
+		err = errors.WithMessagef(err, "explain sql:%s", selectSql)
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+		raw := make(map[string]string, len(columns))
+		for i, column := range columns {
+			raw[strings.ToLower(column)] = values[i].String
+		}
+		plans = append(plans, newExplainPlan(raw))
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+func newExplainPlan(raw map[string]string) (plan ExplainPlan) {
+	plan = ExplainPlan{
+		Table:  raw["table"],
+		Type:   raw["type"],
+		Key:    raw["key"],
+		Rows:   cast.ToInt64(raw["rows"]),
+		Extra:  raw["extra"],
+		Detail: raw["detail"],
+		Raw:    raw,
+	}
+	if plan.Detail == "" { // mysql
+		plan.FullTableScan = strings.EqualFold(plan.Type, "ALL")
+		return plan
+	}
+	// sqlite: SCAN user / SCAN TABLE user(旧版本) 为全表扫描, SCAN user USING INDEX 为索引扫描
+	words := strings.Fields(plan.Detail)
+	if len(words) > 1 && strings.EqualFold(words[1], "TABLE") {
+		words = append(words[:1], words[2:]...)
+	}
+	if len(words) > 1 {
+		plan.Table = words[1]
+	}
+	plan.FullTableScan = len(words) > 0 && strings.EqualFold(words[0], "SCAN") && !strings.Contains(plan.Detail, " USING ")
+	return plan
+}
+
+// Explain 分析查询sql执行计划
+func (p FirstParam) Explain() (plans ExplainPlans, err error) {
+	sql, err := p.ToSQL(p._Fields)
+	if err != nil {
+		return nil, err
+	}
+	return Explain(p.GetHandlerWithInitTable(), sql)
+}
+
+// Explain 分析查询sql执行计划
+func (p ListParam) Explain() (plans ExplainPlans, err error) {
+	sql, err := p.ToSQL(p._Fields)
+	if err != nil {
+		return nil, err
+	}
+	return Explain(p.GetHandlerWithInitTable(), sql)
+}
+
+// Explain 分析统计sql执行计划
+func (p TotalParam) Explain() (plans ExplainPlans, err error) {
+	sql, err := p.ToSQL(p._Fields)
+	if err != nil {
+		return nil, err
+	}
+	return Explain(p.GetHandlerWithInitTable(), sql)
+}
+
+// Explain 分析存在性查询sql执行计划
+func (p ExistsParam) Explain() (plans ExplainPlans, err error) {
+	sql, err := p.ToSQL(p._Fields)
+	if err != nil {
+		return nil, err
+	}
+	return Explain(p.GetHandlerWithInitTable(), sql)
+}
+
+// Explain 分析分页查询的统计sql、列表sql执行计划,结果按统计、列表顺序合并
+func (p PaginationParam) Explain() (plans ExplainPlans, err error) {
+	totalSql, listSql, err := p.ToSQL(p._Fields)
+	if err != nil {
+		return nil, err
+	}
+	handler := p.GetHandlerWithInitTable()
+	for _, sql := range []string{totalSql, listSql} {
+		subPlans, err := Explain(handler, sql)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, subPlans...)
+	}
+	return plans, nil
+}
@@ -0,0 +1,257 @@
+package sqlbuilder
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pkg/errors"
+)
+
+// RowFn 逐行处理函数,使用 ScanRow 读取当前行
+type RowFn func(rows Rows) (err error)
+
+var ErrIterateStop = errors.New("iterate stop") // 在 RowFn 中返回该错误可提前结束遍历,遍历方法返回nil
+
+// Iterate 流式读取查询结果,不会一次性加载到内存,适用于大数据量导出
+func (p ListParam) Iterate(ctx context.Context, rowFn RowFn) (err error) {
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "ListParam.iterate",
+		Fn: func(mctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			sql, err := p.ToSQL(*fsRef)
+			if err != nil {
+				return err
+			}
+			_, err = queryRows(ctx, p.GetHandlerWithInitTable(), sql, rowFn)
+			if err != nil {
+				return err
+			}
+			err = mctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		if errors.Is(err, ErrIterateStop) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// IterateByPrimaryKey 按主键分批读取(where pk>last order by pk limit chunkSize),每批查询结束即释放游标,避免mysql长时间占用游标,要求表为单列主键
+func (p ListParam) IterateByPrimaryKey(ctx context.Context, chunkSize int, rowFn RowFn) (err error) {
+	if chunkSize <= 0 {
+		err = errors.Errorf("ListParam.IterateByPrimaryKey chunkSize must be greater than 0,got:%d", chunkSize)
+		return err
+	}
+	table := p.GetTable()
+	primary, exists := table.Indexs.GetPrimary()
+	if !exists {
+		err = errors.Errorf("ListParam.IterateByPrimaryKey table(%s) primary key not found", table.Name)
+		return err
+	}
+	columnNames := primary.ColumnNames(table)
+	if len(columnNames) != 1 {
+		err = errors.Errorf("ListParam.IterateByPrimaryKey table(%s) required single column primary key,got:%v", table.Name, columnNames)
+		return err
+	}
+	columnName := columnNames[0]
+	fullName := fmt.Sprintf("%s.%s", table.BaseName(), columnName)
+	aliasNames := []string{columnName}
+	if col, ok := table.Columns.GetByDbName(columnName); ok && col.FieldName != "" {
+		aliasNames = append(aliasNames, col.FieldName) // 查询列可能使用了字段名作为别名
+	}
+
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "ListParam.iterateByPrimaryKey",
+		Fn: func(mctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			handler := p.GetHandlerWithInitTable()
+			var last any
+			for {
+				chunkParam := p
+				chunkParam.builderFns = append(SelectBuilderFns{}, p.builderFns...)
+				lastValue := last
+				chunkParam = *chunkParam.WithBuilderFns(func(ds *goqu.SelectDataset) *goqu.SelectDataset {
+					if lastValue != nil {
+						ds = ds.Where(goqu.I(fullName).Gt(lastValue))
+					}
+					return ds.ClearOrder().Order(goqu.I(fullName).Asc()).ClearOffset().Limit(uint(chunkSize))
+				})
+				sql, err := chunkParam.ToSQL(*fsRef)
+				if err != nil {
+					return err
+				}
+				recorder := &_PrimaryKeyRecorder{aliasNames: aliasNames}
+				count, err := queryRows(ctx, handler, sql, func(rows Rows) (err error) {
+					recorder.Rows = rows
+					recorder.recorded = false
+					err = rowFn(recorder)
+					if err != nil {
+						return err
+					}
+					if !recorder.recorded {
+						err = errors.Errorf("ListParam.IterateByPrimaryKey primary key(%s) not scanned,please use ScanRow in RowFn and select primary key", columnName)
+						return err
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				if count < int64(chunkSize) {
+					break
+				}
+				last = recorder.value
+			}
+			err = mctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		if errors.Is(err, ErrIterateStop) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// queryRows 执行流式查询,逐行回调
+func queryRows(ctx context.Context, handler Handler, sql string, rowFn RowFn) (count int64, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	queryer, ok := handler.OriginalHandler().(RowsQueryer)
+	if !ok {
+		err = errors.Errorf("handler(%T) not implement RowsQueryer", handler.OriginalHandler())
+		return 0, err
+	}
+	rows, err := queryer.QueryRows(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			return count, err
+		}
+		err = rowFn(rows)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// _PrimaryKeyRecorder 记录每行扫描得到的主键值,用于计算下一批次的起始位置
+type _PrimaryKeyRecorder struct {
+	Rows
+	aliasNames []string
+	index      int
+	indexReady bool
+	value      any
+	recorded   bool
+}
+
+func (r *_PrimaryKeyRecorder) Scan(dest ...any) (err error) {
+	err = r.Rows.Scan(dest...)
+	if err != nil {
+		return err
+	}
+	if !r.indexReady {
+		r.index = -1
+		columns, err := r.Rows.Columns()
+		if err != nil {
+			return err
+		}
+		for i, column := range columns {
+			if contains(r.aliasNames, column) {
+				r.index = i
+				break
+			}
+		}
+		r.indexReady = true
+	}
+	if r.index < 0 || r.index >= len(dest) {
+		return nil
+	}
+	value := reflect.Indirect(reflect.ValueOf(dest[r.index])).Interface()
+	if b, ok := value.([]byte); ok {
+		value = string(b) // mysql 驱动默认返回[]byte,转为字符串后作为条件值
+	}
+	if IsNil(value) {
+		return nil
+	}
+	r.value = value
+	r.recorded = true
+	return nil
+}
+
+// newIterateRow 初始化一行数据的接收变量,兼容指针类型
+func newIterateRow[M any]() (row M, dst any) {
+	rt := reflect.TypeFor[M]()
+	if rt.Kind() == reflect.Ptr {
+		row = reflect.New(rt.Elem()).Interface().(M)
+		return row, row
+	}
+	return row, nil
+}
+
+func iterateRowFn[M any](fn func(row M) (err error)) RowFn {
+	return func(rows Rows) (err error) {
+		row, dst := newIterateRow[M]()
+		if dst == nil {
+			dst = &row
+		}
+		err = ScanRow(rows, dst)
+		if err != nil {
+			return err
+		}
+		return fn(row)
+	}
+}
+
+// Iterate 流式读取,每行扫描为 M 后回调
+func Iterate[M any](ctx context.Context, p *ListParam, fn func(row M) (err error)) (err error) {
+	p.WithResultDest(new(M))
+	return p.Iterate(ctx, iterateRowFn(fn))
+}
+
+// IterateByPrimaryKey 按主键分批流式读取,每行扫描为 M 后回调
+func IterateByPrimaryKey[M any](ctx context.Context, p *ListParam, chunkSize int, fn func(row M) (err error)) (err error) {
+	p.WithResultDest(new(M))
+	return p.IterateByPrimaryKey(ctx, chunkSize, iterateRowFn(fn))
+}
+
+// IterateSeq 以 iter.Seq2 形式流式读取,出错时返回一次错误后结束
+func IterateSeq[M any](ctx context.Context, p *ListParam) iter.Seq2[M, error] {
+	return func(yield func(M, error) bool) {
+		err := Iterate(ctx, p, func(row M) (err error) {
+			if !yield(row, nil) {
+				return ErrIterateStop
+			}
+			return nil
+		})
+		if err != nil {
+			var zero M
+			yield(zero, err)
+		}
+	}
+}
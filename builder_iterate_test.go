@@ -0,0 +1,47 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+)
+
+func TestIterate(t *testing.T) {
+	table := newIterateTable(t, 7)
+	ctx := context.Background()
+	t.Run("iterate", func(t *testing.T) {
+		ids := make([]int, 0)
+		err := sqlbuilder.Iterate(ctx, sqlbuilder.NewListBuilder(table), func(row iterateUser) (err error) {
+			ids = append(ids, row.Id)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7}, ids)
+	})
+
+	t.Run("by primary key", func(t *testing.T) {
+		names := make([]string, 0)
+		err := sqlbuilder.IterateByPrimaryKey(ctx, sqlbuilder.NewListBuilder(table), 3, func(row *iterateUser) (err error) {
+			names = append(names, row.Name)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, names, 7)
+		require.Equal(t, "user7", names[6])
+	})
+
+	t.Run("seq break", func(t *testing.T) {
+		count := 0
+		for row, err := range sqlbuilder.IterateSeq[map[string]any](ctx, sqlbuilder.NewListBuilder(table)) {
+			require.NoError(t, err)
+			require.NotNil(t, row["id"])
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		require.Equal(t, 2, count)
+	})
+}
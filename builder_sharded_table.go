@@ -1,16 +1,56 @@
 package sqlbuilder
 
 import (
+	"cmp"
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx/reflectx"
 	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/suifengpiao14/cache"
+	"golang.org/x/sync/errgroup"
 )
 
 // PaginationParamForShardedTable 从分表/水平分表中获取数据，分表/水平分表指 表结构相同，仅表名不同的多张表
 type ShardedTablePaginationParam struct {
 	PaginationParam
-	withOutTotal bool // 默认统计总数，设置为true时不统计总数
+	withOutTotal       bool          // 默认统计总数，设置为true时不统计总数
+	withUnion          bool          // 使用 union all 合并分表查询,跨分表排序正确且减少查询次数
+	concurrency        int           // 分表并发查询数,默认 ShardedTable_concurrency
+	totalCacheDuration time.Duration // 分表总数缓存时长,按分库、分表分别缓存
+}
+
+// ShardedTable_concurrency 分表默认并发查询数
+var ShardedTable_concurrency = 4
+
+// WithConcurrency 设置分表并发查询数,小于等于0时使用默认值
+func (p *ShardedTablePaginationParam) WithConcurrency(concurrency int) *ShardedTablePaginationParam {
+	p.concurrency = concurrency
+	return p
+}
+
+// WithTotalCacheDuration 缓存各分表总数,历史分表(如按月分表)数据不再变化,翻页时无需重复统计
+func (p *ShardedTablePaginationParam) WithTotalCacheDuration(duration time.Duration) *ShardedTablePaginationParam {
+	p.totalCacheDuration = duration
+	return p
+}
+
+func (p ShardedTablePaginationParam) getConcurrency() int {
+	if p.concurrency > 0 {
+		return p.concurrency
+	}
+	return max(ShardedTable_concurrency, 1)
+}
+
+// WithUnion 分表数据通过一条 union all 语句查询,外层统一排序、分页
+func (p *ShardedTablePaginationParam) WithUnion(withUnion bool) *ShardedTablePaginationParam {
+	p.withUnion = withUnion
+	return p
 }
 
 func (p *ShardedTablePaginationParam) WithOutTotal(withOutTotal bool) *ShardedTablePaginationParam {
@@ -64,7 +104,18 @@ func (shardedT shardedTableSingleTablePagination) count(fs Fields) (count int64,
 	if err != nil {
 		return 0, err
 	}
-	count, err = handler.Count(totalSql)
+	identity := handlerIdentity(shardedT.table.GetHandler())
+	if shardedT.p.totalCacheDuration <= 0 || identity == "" {
+		return handler.Count(totalSql)
+	}
+	cacheKey := fmt.Sprintf("%s:%s", identity, totalSql) // 分库时各库执行相同 sql,按库区分
+	count, err = cache.RememberWithCacheInstance(CacheInstance, cacheKey, func() (count int64, duration time.Duration, err error) {
+		count, err = handler.Count(totalSql)
+		if err != nil {
+			return 0, 0, err
+		}
+		return count, shardedT.p.totalCacheDuration, nil
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -119,7 +170,7 @@ func (shardedT shardedTableSingleTablePagination) TotalSQL(fs Fields) (totalSql
 
 func (shardedT shardedTableSingleTablePagination) ListSQL(fs Fields, offset, limit int) (listSQL string, err error) {
 	offset, limit = max(offset, 0), max(limit, 0)
-	listBuilder := NewListBuilder(shardedT.table).WithCustomFieldsFn(shardedT.p.customFieldsFns...).AppendFields(fs...).WithBuilderFns(shardedT.p.builderFns...)
+	listBuilder := NewListBuilder(shardedT.table).WithCustomFieldsFn(shardedT.p.customFieldsFns...).AppendFields(fs...).WithBuilderFns(shardedT.p.builderFns...).WithProjection(shardedT.p.projection...)
 	listBuilder = listBuilder.WithBuilderFns(func(ds *goqu.SelectDataset) *goqu.SelectDataset {
 		ds = ds.Offset(uint(offset)).Limit(uint(limit)) //根据实际情况 重置limit和offset
 
@@ -134,6 +185,50 @@ func (shardedT shardedTableSingleTablePagination) ListSQL(fs Fields, offset, lim
 
 type ShardedTablePaginations []shardedTableSingleTablePagination
 
+// parallel 按并发数限制并发执行各分表操作
+func (shardedTs ShardedTablePaginations) parallel(concurrency int, fn func(i int, shardedT shardedTableSingleTablePagination) (err error)) (err error) {
+	g := new(errgroup.Group)
+	g.SetLimit(concurrency)
+	for i, shardedT := range shardedTs {
+		g.Go(func() error {
+			return fn(i, shardedT)
+		})
+	}
+	return g.Wait()
+}
+
+// counts 并发统计各分表总数
+func (shardedTs ShardedTablePaginations) counts(concurrency int) (counts []int64, err error) {
+	counts = make([]int64, len(shardedTs))
+	err = shardedTs.parallel(concurrency, func(i int, shardedT shardedTableSingleTablePagination) (err error) {
+		counts[i], err = shardedT.Count()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// lists 并发查询各分表数据,limitFn 返回各分表的偏移量与数量
+func (shardedTs ShardedTablePaginations) lists(concurrency int, rt reflect.Type, limitFn func(i int) (offset int, limit int)) (subResults []reflect.Value, err error) {
+	subResults = make([]reflect.Value, len(shardedTs))
+	err = shardedTs.parallel(concurrency, func(i int, shardedT shardedTableSingleTablePagination) (err error) {
+		offset, limit := limitFn(i)
+		subResult := reflect.New(rt)
+		err = shardedT.List(subResult.Interface(), offset, limit)
+		if err != nil {
+			return err
+		}
+		subResults[i] = subResult.Elem()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subResults, nil
+}
+
 var ErrPaginationSizeRequired = errors.New("pagination size required")
 
 func (p ShardedTablePaginationParam) Pagination(result any) (totalCount int64, err error) {
@@ -155,13 +250,21 @@ func (p ShardedTablePaginationParam) Pagination(result any) (totalCount int64, e
 		tableNames = []string{tableConfig.DBName.Name}
 	}
 
-	//生成统计总数sql
+	tables := make([]TableConfig, 0, len(tableNames))
 	for _, tableName := range tableNames {
-		table := tableConfig.WithTableName(tableName)
+		tables = append(tables, tableConfig.WithTableName(tableName))
+	}
+	tables, err = routeShardedHandlers(tables, p._Fields) // 分库时每个分库的分表单独查询
+	if err != nil {
+		return 0, err
+	}
+	//生成统计总数sql
+	for _, table := range tables {
 		shardedTablePagination := shardedTableSingleTablePagination{
 			table: table,
 			p:     p,
 		}
+		shardedTablePagination.p._Table = table // 使用分表(分库)句柄
 		shardedTablePaginations = append(shardedTablePaginations, shardedTablePagination)
 	}
 
@@ -170,44 +273,342 @@ func (p ShardedTablePaginationParam) Pagination(result any) (totalCount int64, e
 		err = ErrPaginationSizeRequired
 		return 0, err
 	}
+	if p.withUnion {
+		if isShardedHandler(tableConfig._handler) {
+			err = errors.Errorf("ShardedTablePaginationParam table:%s union mode not supported across database shards", tableConfig.Name)
+			return 0, err
+		}
+		return p.unionPagination(tableNames, result)
+	}
 	offset := int64(pageIndex * size)
 	limit := size
-	rvArr := reflect.MakeSlice(rt, 0, 0)
+	orderKeys, err := p.orderKeys()
+	if err != nil {
+		return 0, err
+	}
+	if len(orderKeys) == 0 && p.withOutTotal { // 不统计总数且无排序要求,依次查询分表,满足数量即退出
+		rvArr, err := p.serialList(shardedTablePaginations, rt, offset, limit)
+		if err != nil {
+			return 0, err
+		}
+		rv.Set(rvArr)
+		return 0, nil
+	}
+	var counts []int64
+	if !p.withOutTotal {
+		counts, err = shardedTablePaginations.counts(p.getConcurrency())
+		if err != nil {
+			return 0, err
+		}
+		for _, count := range counts {
+			totalCount += count
+		}
+	}
+	var rvArr reflect.Value
+	if len(orderKeys) == 0 {
+		rvArr, err = p.concatList(shardedTablePaginations, counts, rt, offset, limit)
+	} else {
+		rvArr, err = p.mergeList(shardedTablePaginations, counts, rt, orderKeys, offset, limit)
+	}
+	if err != nil {
+		return 0, err
+	}
+	rv.Set(rvArr)
+	return totalCount, nil
+}
+
+// serialList 依次查询分表,每个分表先计数再按剩余偏移量、数量查询,满足数量后不再查询后续分表
+func (p ShardedTablePaginationParam) serialList(shardedTablePaginations ShardedTablePaginations, rt reflect.Type, offset int64, limit uint) (rvArr reflect.Value, err error) {
+	rvArr = reflect.MakeSlice(rt, 0, 0)
 	for _, shardedTablePagination := range shardedTablePaginations {
-		if p.withOutTotal && limit <= 0 { // 已满足查询数量，退出循环
+		if limit <= 0 { // 已满足查询数量，退出循环
 			break
 		}
 		count, err := shardedTablePagination.Count()
 		if err != nil {
-			return 0, err
+			return rvArr, err
 		}
-		totalCount += count
 		if count <= offset {
 			offset = offset - count
 			continue
 		}
+		subResult := reflect.New(rt).Interface()
+		err = shardedTablePagination.List(subResult, int(offset), int(limit))
+		if err != nil {
+			return rvArr, err
+		}
+		beforCount := rvArr.Len()
+		rvArr = reflect.AppendSlice(rvArr, reflect.Indirect(reflect.ValueOf(subResult)))
+		afterCount := rvArr.Len()
+		realCount := uint(afterCount - beforCount) // 获取本次查询的实际数量
+		// 更新偏移量与剩余数量
+		offset = max(offset-int64(realCount), 0) // 入参pageIndex=0,size=100,实际查到5条，则下一次查询偏移量还是0，只是limit 100-5=95
+		limit = limit - realCount
+	}
+	return rvArr, nil
+}
 
-		if limit > 0 { // 剩余数量大于0，才执行查询
-			subResult := reflect.New(rt).Interface()
-			err = shardedTablePagination.List(subResult, int(offset), int(limit))
-			if err != nil {
-				return 0, err
+// concatList 按分表顺序拼接,根据各分表总数计算命中分表的偏移量与数量后并发查询
+func (p ShardedTablePaginationParam) concatList(shardedTablePaginations ShardedTablePaginations, counts []int64, rt reflect.Type, offset int64, limit uint) (rvArr reflect.Value, err error) {
+	hits := ShardedTablePaginations{}
+	hitOffsets, hitLimits := make([]int, 0), make([]int, 0)
+	for i, shardedTablePagination := range shardedTablePaginations {
+		if limit <= 0 {
+			break
+		}
+		count := counts[i]
+		if count <= offset {
+			offset = offset - count
+			continue
+		}
+		realCount := min(uint(count-offset), limit)
+		hits = append(hits, shardedTablePagination)
+		hitOffsets, hitLimits = append(hitOffsets, int(offset)), append(hitLimits, int(realCount))
+		offset = 0
+		limit = limit - realCount
+	}
+	subResults, err := hits.lists(p.getConcurrency(), rt, func(i int) (offset int, limit int) {
+		return hitOffsets[i], hitLimits[i]
+	})
+	if err != nil {
+		return rvArr, err
+	}
+	rvArr = reflect.MakeSlice(rt, 0, 0)
+	for _, subResult := range subResults {
+		rvArr = reflect.AppendSlice(rvArr, subResult)
+	}
+	return rvArr, nil
+}
+
+// mergeList 各分表按相同排序取前 offset+limit 条,再多路归并取目标页,保证跨分表排序正确
+func (p ShardedTablePaginationParam) mergeList(shardedTablePaginations ShardedTablePaginations, counts []int64, rt reflect.Type, orderKeys shardedOrderKeys, offset int64, limit uint) (rvArr reflect.Value, err error) {
+	need := offset + int64(limit)
+	hits := ShardedTablePaginations{}
+	hitLimits := make([]int, 0)
+	for i, shardedTablePagination := range shardedTablePaginations {
+		shardedLimit := need
+		if counts != nil {
+			shardedLimit = min(counts[i], need)
+		}
+		if shardedLimit <= 0 {
+			continue
+		}
+		hits = append(hits, shardedTablePagination)
+		hitLimits = append(hitLimits, int(shardedLimit))
+	}
+	subResults, err := hits.lists(p.getConcurrency(), rt, func(i int) (offset int, limit int) {
+		return 0, hitLimits[i]
+	})
+	if err != nil {
+		return rvArr, err
+	}
+	rvArr = orderKeys.merge(rt, subResults, offset, need)
+	return rvArr, nil
+}
+
+// merge 多路归并各分表已排序结果,跳过前 offset 条,最多归并 need 条(need<0 时归并全部)
+func (keys shardedOrderKeys) merge(rt reflect.Type, subResults []reflect.Value, offset int64, need int64) (rvArr reflect.Value) {
+	rvArr = reflect.MakeSlice(rt, 0, 0)
+	cursors := make([]int, len(subResults))
+	for merged := int64(0); need < 0 || merged < need; merged++ {
+		next := -1
+		for i, subResult := range subResults {
+			if cursors[i] >= subResult.Len() {
+				continue
 			}
-			beforCount := rvArr.Len()
-			rvArr = reflect.AppendSlice(rvArr, reflect.Indirect(reflect.ValueOf(subResult)))
-			afterCount := rvArr.Len()
-			realCount := uint(afterCount - beforCount) // 获取本次查询的实际数量
-			// 更新偏移量与剩余数量
-			offset = max(offset-int64(realCount), 0) // 入参pageIndex=0,size=100,实际查到5条，则下一次查询偏移量还是0，只是limit 100-5=95
-			limit = limit - realCount
+			if next == -1 || keys.compare(subResult.Index(cursors[i]), subResults[next].Index(cursors[next])) < 0 {
+				next = i
+			}
+		}
+		if next == -1 { // 所有分表数据已取完
+			break
+		}
+		if merged >= offset {
+			rvArr = reflect.Append(rvArr, subResults[next].Index(cursors[next]))
+		}
+		cursors[next]++
+	}
+	return rvArr
+}
+
+// shardedOrderKey 跨分表归并使用的排序列
+type shardedOrderKey struct {
+	columnName string
+	fieldName  string
+	asc        bool
+}
+
+type shardedOrderKeys []shardedOrderKey
 
+// orderKeys 解析排序字段
+func (p ShardedTablePaginationParam) orderKeys() (orderKeys shardedOrderKeys, err error) {
+	table := p.GetTable()
+	fs := p._Fields.Builder(p.context, SCENE_SQL_SELECT, table, p.customFieldsFns)
+	orderKeys, err = newShardedOrderKeys(table, fs.Order())
+	if err != nil {
+		err = errors.WithMessage(err, "ShardedTablePaginationParam use WithUnion instead")
+		return nil, err
+	}
+	return orderKeys, nil
+}
+
+// newShardedOrderKeys 解析排序表达式,仅支持按列排序,表达式排序无法在内存中归并
+func newShardedOrderKeys(table TableConfig, orderedExpressions []exp.OrderedExpression) (orderKeys shardedOrderKeys, err error) {
+	for _, orderedExpression := range orderedExpressions {
+		identifier, ok := orderedExpression.SortExpression().(exp.IdentifierExpression)
+		if !ok {
+			err = errors.Errorf("table:%s unsupported order expression %T across sharded tables", table.Name, orderedExpression.SortExpression())
+			return nil, err
+		}
+		orderKey := shardedOrderKey{
+			columnName: cast.ToString(identifier.GetCol()),
+			asc:        orderedExpression.IsAsc(),
 		}
+		if col, exists := table.Columns.GetByDbName(orderKey.columnName); exists {
+			orderKey.fieldName = col.FieldName
+		}
+		orderKeys = append(orderKeys, orderKey)
 	}
-	rv.Set(rvArr)
-	return totalCount, nil
+	return orderKeys, nil
+}
+
+func (keys shardedOrderKeys) compare(a reflect.Value, b reflect.Value) int {
+	for _, key := range keys {
+		c := compareShardedValue(key.value(a), key.value(b))
+		if c == 0 {
+			continue
+		}
+		if !key.asc {
+			c = -c
+		}
+		return c
+	}
+	return 0
 }
+
+// value 从结果行(map/结构体)中取排序列值,依次按列名、字段名查找
+func (key shardedOrderKey) value(row reflect.Value) any {
+	row = indirectValue(row)
+	names := []string{key.columnName, key.fieldName}
+	switch row.Kind() {
+	case reflect.Map:
+		if row.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			v := row.MapIndex(reflect.ValueOf(name).Convert(row.Type().Key()))
+			if v.IsValid() {
+				return valueInterface(v)
+			}
+		}
+	case reflect.Struct:
+		v, ok := structFieldByName(row, key.columnName, key.fieldName)
+		if ok {
+			return valueInterface(v)
+		}
+	}
+	return nil
+}
+
+// structFieldByName 按 db 标签查找结构体字段(依次按列名、字段名),都不存在时按字段名忽略大小写匹配结构体属性名
+// 注意 structScanMapper.FieldByName 在名称不存在时返回结构体本身,不能用于判断字段是否存在
+func structFieldByName(row reflect.Value, columnName string, fieldName string) (v reflect.Value, ok bool) {
+	typeMap := structScanMapper.TypeMap(row.Type())
+	for _, name := range []string{columnName, fieldName} {
+		if name == "" {
+			continue
+		}
+		if fi, exists := typeMap.Names[name]; exists {
+			return reflectx.FieldByIndexesReadOnly(row, fi.Index), true
+		}
+	}
+	if fieldName == "" {
+		return reflect.Value{}, false
+	}
+	for _, fi := range typeMap.Index {
+		if fi.Field.Name != "" && strings.EqualFold(fi.Field.Name, fieldName) {
+			return reflectx.FieldByIndexesReadOnly(row, fi.Index), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func valueInterface(v reflect.Value) any {
+	v = indirectValue(v)
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// compareShardedValue 比较排序列值,nil 最小,时间、数字按值比较,其余按字符串比较
+func compareShardedValue(a any, b any) int {
+	if bs, ok := a.([]byte); ok {
+		a = string(bs)
+	}
+	if bs, ok := b.([]byte); ok {
+		b = string(bs)
+	}
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt)
+		}
+	}
+	_, aIsString := a.(string)
+	_, bIsString := b.(string)
+	if !aIsString && !bIsString {
+		af, aErr := cast.ToFloat64E(a)
+		bf, bErr := cast.ToFloat64E(b)
+		if aErr == nil && bErr == nil {
+			return cmp.Compare(af, bf)
+		}
+	}
+	return cmp.Compare(cast.ToString(a), cast.ToString(b))
+}
+
+// unionPagination 各分表查询合并为一条union all 语句,总数为各分表计数之和
+func (p ShardedTablePaginationParam) unionPagination(tableNames []string, result any) (totalCount int64, err error) {
+	tableConfig := p.GetTable()
+	unionBuilder := NewUnionBuilder(tableConfig).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...)
+	unionBuilder.context = p.context
+	for _, tableName := range tableNames {
+		listBuilder := NewListBuilder(tableConfig.WithTableName(tableName)).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...).WithBuilderFns(p.builderFns...).WithProjection(p.projection...)
+		listBuilder.context = p.context
+		unionBuilder = unionBuilder.AppendListParams(listBuilder)
+	}
+	if p.withOutTotal {
+		err = unionBuilder.List(result)
+		if err != nil {
+			return 0, err
+		}
+		return 0, nil
+	}
+	return unionBuilder.Pagination(result)
+}
+
 func (p ShardedTablePaginationParam) ListSQL(fs Fields, tableConfig TableConfig, offset uint, limit uint) (listSQL string, err error) {
-	listBuilder := NewListBuilder(tableConfig).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...).WithBuilderFns(p.builderFns...)
+	listBuilder := NewListBuilder(tableConfig).WithCustomFieldsFn(p.customFieldsFns...).AppendFields(p._Fields...).WithBuilderFns(p.builderFns...).WithProjection(p.projection...)
 	listBuilder = listBuilder.WithBuilderFns(func(ds *goqu.SelectDataset) *goqu.SelectDataset {
 		ds = ds.Limit(limit).Offset(offset)
 		return ds
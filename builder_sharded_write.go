@@ -0,0 +1,217 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// ErrShardedTableRoute 分表路由失败,如新增时分表键缺失导致无法确定唯一分表
+var ErrShardedTableRoute = errors.New("sharded table route failed")
+
+// routeShardedTables 按字段路由分表、分库,分表(分库)键存在时一般返回单个分表,否则返回多个分表(fan-out);返回的分表不再具备分表策略
+func (t TableConfig) routeShardedTables(fs Fields) (tables []TableConfig, err error) {
+	if !t.isShardedTable() {
+		return routeShardedHandlers([]TableConfig{t}, fs)
+	}
+	values := make([]Field, 0, len(fs))
+	for _, f := range fs {
+		if f != nil {
+			values = append(values, *f)
+		}
+	}
+	for _, tableName := range t.getShardedTableNames(values...) {
+		tables = append(tables, t.WithTableName(tableName).WithShardedTableNameFn(nil))
+	}
+	if len(tables) == 0 {
+		err = errors.WithMessagef(ErrShardedTableRoute, "table:%s no sharded table matched", t.Name)
+		return nil, err
+	}
+	return routeShardedHandlers(tables, fs)
+}
+
+// routeShardedTable 写入数据必须路由到唯一分表(分库),开启自动建表时分表不存在则创建
+func (t TableConfig) routeShardedTable(fs Fields) (table TableConfig, err error) {
+	tables, err := t.routeShardedTables(fs)
+	if err != nil {
+		return table, err
+	}
+	if len(tables) != 1 {
+		tableNames := make([]string, 0, len(tables))
+		for _, table := range tables {
+			tableNames = append(tableNames, table.Name)
+		}
+		err = errors.WithMessagef(ErrShardedTableRoute, "table:%s required single sharded table,got %d:%s", t.Name, len(tables), strings.Join(tableNames, ","))
+		return table, err
+	}
+	table = tables[0]
+	if t.shardedTableAutoCreate {
+		err = table.createShardedTableIfNotExists()
+		if err != nil {
+			return table, err
+		}
+	}
+	return table, nil
+}
+
+var shardedTableCreated sync.Map
+
+// createShardedTableIfNotExists 分表不存在时使用 GenerateDDL 建表,每个库的每个分表只检测一次
+func (t TableConfig) createShardedTableIfNotExists() (err error) {
+	handler := t.GetHandler()
+	identity := handlerIdentity(handler)
+	createdKey := fmt.Sprintf("%s:%s", identity, t.Name) // 不同库存在同名分表
+	if _, ok := shardedTableCreated.Load(createdKey); ok && identity != "" {
+		return nil
+	}
+	sql, _, err := Driver(handler.GetDialector()).GoquDialect().From(t.Name).Select(goqu.L("1")).Limit(1).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = handler.Exists(sql)
+	if err != nil {
+		if !isTableNotExistsError(err) {
+			err = errors.WithMessagef(err, "check sharded table:%s exists failed", t.Name)
+			return err
+		}
+		ddl, err := t.GenerateDDL()
+		if err != nil {
+			return err
+		}
+		err = handler.Exec(ddl)
+		if err != nil {
+			err = errors.WithMessagef(err, "create sharded table:%s failed", t.Name)
+			return err
+		}
+	}
+	if identity != "" { // 无法识别所在库时每次检测
+		shardedTableCreated.Store(createdKey, struct{}{})
+	}
+	return nil
+}
+
+const mysql_ER_NO_SUCH_TABLE = 1146
+
+// isTableNotExistsError 表不存在错误,其它错误(如连接失败)不能作为建表依据
+func isTableNotExistsError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysql_ER_NO_SUCH_TABLE
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return strings.Contains(sqliteErr.Error(), "no such table")
+	}
+	return false
+}
+
+// shardedUpdate 更新路由到分表,未指定分表键时更新所有匹配分表
+func (p UpdateParam) shardedUpdate() (rowsAffected int64, err error) {
+	if p.onlyChanged {
+		err = errors.WithMessagef(ErrOnlyChangedSharded, "table:%s", p._Table.Name)
+		return 0, err
+	}
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return 0, err
+	}
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		subRowsAffected, err := shardedP.Update()
+		if err != nil {
+			return rowsAffected, err
+		}
+		rowsAffected += subRowsAffected
+	}
+	return rowsAffected, nil
+}
+
+// shardedBatches 批量新增按行路由分组,每行必须路由到唯一分表(分库),分组按首次出现顺序
+func (p BatchInsertParam) shardedBatches() (batches []BatchInsertParam, err error) {
+	indexes := make(map[string]int)
+	for _, fs := range p.rowFields {
+		table, err := p._Table.routeShardedTable(fs)
+		if err != nil {
+			return nil, err
+		}
+		key := table.Name
+		if shardedHandler, ok := p._Table._handler.(*ShardedHandler); ok { // 不同分库存在同名分表
+			key = fmt.Sprintf("%s@%s", key, strings.Join(shardedHandler.shardKeys(fs), ","))
+		}
+		i, ok := indexes[key]
+		if !ok {
+			batch := p
+			batch._Table = table
+			batch.rowFields = nil
+			i = len(batches)
+			indexes[key] = i
+			batches = append(batches, batch)
+		}
+		batches[i].rowFields = append(batches[i].rowFields, fs)
+	}
+	return batches, nil
+}
+
+// shardedInsertWithLastId 批量新增路由到分表,各分表分别写入
+func (p BatchInsertParam) shardedInsertWithLastId() (lastInsertId uint64, rowsAffected int64, err error) {
+	batches, err := p.shardedBatches()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(batches) == 0 {
+		return 0, 0, ErrBatchInsertDataIsNil
+	}
+	for _, batch := range batches {
+		subLastInsertId, subRowsAffected, err := batch.insertWithLastId()
+		if err != nil {
+			return lastInsertId, rowsAffected, err
+		}
+		lastInsertId = subLastInsertId
+		rowsAffected += subRowsAffected
+	}
+	return lastInsertId, rowsAffected, nil
+}
+
+// shardedDelete 删除路由到分表,未指定分表键时删除所有匹配分表
+func (p DeleteParam) shardedDelete() (rowsAffected int64, err error) {
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return 0, err
+	}
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		subRowsAffected, err := shardedP.Delete()
+		if err != nil {
+			return rowsAffected, err
+		}
+		rowsAffected += subRowsAffected
+	}
+	return rowsAffected, nil
+}
+
+// shardedFirst 按分表顺序查询,找到即返回
+func (p FirstParam) shardedFirst(result any) (exists bool, err error) {
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return false, err
+	}
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		exists, err = shardedP.First(result)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,114 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func TestShardedWrite(t *testing.T) {
+	db := sqlbuildertest.New(t)
+	// 按id奇偶分表,未指定id时返回所有分表
+	shardedTableNameFn := func(fs ...sqlbuilder.Field) (shardedTableNames []string) {
+		for _, f := range fs {
+			if f.Name != "id" {
+				continue
+			}
+			val, _ := f.GetValue(sqlbuilder.Layer_get_value_before_db)
+			if id := cast.ToInt(val); id > 0 {
+				return []string{fmt.Sprintf("sharded_user_%d", id%2)}
+			}
+		}
+		return []string{"sharded_user_0", "sharded_user_1"}
+	}
+	table := newUserTableConfig("sharded_user").WithHandler(db.Handler()).WithShardedTableNameFn(shardedTableNameFn).WithShardedTableAutoCreate(true)
+
+	for _, id := range []int{1, 2, 3} {
+		_, _, err := sqlbuilder.NewInsertBuilder(table).AppendFields(NewIterateUserId(id), NewIterateUserName(fmt.Sprintf("user%d", id))).Insert()
+		require.NoError(t, err)
+	}
+	var count int
+	require.NoError(t, db.SqlDB().QueryRow("select count(*) from sharded_user_1").Scan(&count))
+	require.Equal(t, 2, count)
+
+	t.Run("insert without sharded key", func(t *testing.T) {
+		_, _, err := sqlbuilder.NewInsertBuilder(table).AppendFields(NewIterateUserName("noKey")).Insert()
+		require.ErrorIs(t, err, sqlbuilder.ErrShardedTableRoute)
+	})
+
+	t.Run("update single sharded table", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).AppendFields(NewIterateUserId(3).ShieldUpdate(true).AppendWhereFn(sqlbuilder.ValueFnForward), NewIterateUserName("user3x")).Update()
+		require.NoError(t, err)
+		require.EqualValues(t, 1, rowsAffected)
+		user := iterateUser{}
+		exists, err := sqlbuilder.NewFirstBuilder(table).AppendFields(NewIterateUserId(3).AppendWhereFn(sqlbuilder.ValueFnForward)).First(&user)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, "user3x", user.Name)
+	})
+
+	t.Run("chunked update all sharded tables", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).AppendFields(
+			NewIterateUserName("chunked"),
+			NewIterateUserName("user").ShieldUpdate(true).AppendWhereFn(sqlbuilder.ValueFnWhereLike), // 未指定分表键,更新所有分表
+		).Chunked(1).Update(context.Background())
+		require.NoError(t, err)
+		require.EqualValues(t, 3, rowsAffected)
+		var count int
+		require.NoError(t, db.SqlDB().QueryRow("select count(*) from sharded_user_0 where name='chunked'").Scan(&count))
+		require.Equal(t, 1, count)
+	})
+	t.Run("batch insert route rows to sharded tables", func(t *testing.T) {
+		rows := []sqlbuilder.Fields{
+			{NewIterateUserId(4), NewIterateUserName("batch4")},
+			{NewIterateUserId(5), NewIterateUserName("batch5")},
+			{NewIterateUserId(6), NewIterateUserName("batch6")},
+		}
+		_, err := sqlbuilder.NewBatchInsertBuilder(table).AppendFields(rows...).ToSQL()
+		require.ErrorIs(t, err, sqlbuilder.ErrShardedTableRoute)
+
+		_, rowsAffected, err := sqlbuilder.NewBatchInsertBuilder(table).AppendFields(rows...).InsertWithLastId()
+		require.NoError(t, err)
+		require.EqualValues(t, 3, rowsAffected)
+		var count int
+		require.NoError(t, db.SqlDB().QueryRow("select count(*) from sharded_user_0 where name like 'batch%'").Scan(&count))
+		require.Equal(t, 2, count)
+		require.NoError(t, db.SqlDB().QueryRow("select count(*) from sharded_user_1 where name like 'batch%'").Scan(&count))
+		require.Equal(t, 1, count)
+		err = db.SqlDB().QueryRow("select count(*) from sharded_user").Scan(&count) // 不能写入逻辑表
+		require.ErrorContains(t, err, "no such table")
+	})
+
+	t.Run("batch insert without sharded key", func(t *testing.T) {
+		err := sqlbuilder.NewBatchInsertBuilder(table).AppendFields(sqlbuilder.Fields{NewIterateUserId(7), NewIterateUserName("batch7")}, sqlbuilder.Fields{NewIterateUserName("noKey")}).Exec()
+		require.ErrorIs(t, err, sqlbuilder.ErrShardedTableRoute)
+	})
+}
+
+func TestShardedWriteAutoCreatePerDatabase(t *testing.T) {
+	shardedTableNameFn := func(fs ...sqlbuilder.Field) (shardedTableNames []string) {
+		return []string{"sharded_created_user_0"}
+	}
+	for range 2 { // 不同库存在同名分表,各自建表
+		db := sqlbuildertest.New(t)
+		table := newUserTableConfig("sharded_created_user").WithHandler(db.Handler()).WithShardedTableNameFn(shardedTableNameFn).WithShardedTableAutoCreate(true)
+		_, _, err := sqlbuilder.NewInsertBuilder(table).AppendFields(NewIterateUserId(1), NewIterateUserName("user1")).Insert()
+		require.NoError(t, err)
+		var count int
+		require.NoError(t, db.SqlDB().QueryRow("select count(*) from sharded_created_user_0").Scan(&count))
+		require.Equal(t, 1, count)
+	}
+
+	t.Run("check error is not table missing", func(t *testing.T) {
+		db := sqlbuildertest.New(t)
+		db.SqlDB().Close() // 连接已关闭,不能当作分表不存在去建表
+		table := newUserTableConfig("sharded_created_user").WithHandler(db.Handler()).WithShardedTableNameFn(shardedTableNameFn).WithShardedTableAutoCreate(true)
+		_, _, err := sqlbuilder.NewInsertBuilder(table).AppendFields(NewIterateUserId(1)).Insert()
+		require.ErrorContains(t, err, "check sharded table:sharded_created_user_0 exists failed")
+	})
+}
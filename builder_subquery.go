@@ -0,0 +1,133 @@
+package sqlbuilder
+
+import (
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+)
+
+// SubQueryI 可作为子查询的构造器,*ListParam/*ExistsParam 已实现
+type SubQueryI interface {
+	GetTable() TableConfig
+	SubQuery() (ds *goqu.SelectDataset, err error)
+}
+
+// SubQuery 生成子查询,常用于 col in (select ...),查询列通过 Field.SetSelectColumns 指定
+func (p ListParam) SubQuery() (ds *goqu.SelectDataset, err error) {
+	ds, err = p.makeSelectDataset(p._Fields)
+	if err != nil {
+		return nil, err
+	}
+	return ds.ClearOrder().ClearLimit().ClearOffset(), nil // mysql 不支持 in 子查询中使用 limit,排序对子查询无意义
+}
+
+// SubQuery 生成子查询,常用于 exists (select ...),where 条件可以为空(由关联条件补充)
+func (p ExistsParam) SubQuery() (ds *goqu.SelectDataset, err error) {
+	tableConfig := p.GetTable()
+	fs := p._Fields.Builder(p.context, SCENE_SQL_SELECT, tableConfig, p.customFieldsFns)
+	where, err := fs.Where()
+	if err != nil {
+		err = errors.WithMessagef(err, "ExistsParam.SubQuery(),table:%s", tableConfig.Name)
+		return nil, err
+	}
+	return p.makeSelectDataset(where).ClearOrder().ClearLimit().ClearOffset(), nil
+}
+
+// Correlation 关联子查询条件: 子查询表字段 = 外层表字段
+type Correlation struct {
+	InnerFieldName string // 子查询表字段名
+	OuterFieldName string // 外层字段名,为空时使用当前字段
+}
+
+func (c Correlation) expression(inner TableConfig, f *Field, fs ...*Field) (expression exp.Expression, err error) {
+	innerDBName := inner.GetDBNameByFieldName(c.InnerFieldName)
+	if innerDBName == "" {
+		err = errors.Errorf("correlation inner table(%s) ColumnConfig not found by fieldName:%s", inner.Name, c.InnerFieldName)
+		return nil, err
+	}
+	outer := f
+	if c.OuterFieldName != "" {
+		outerField, ok := Fields(fs).GetByName(c.OuterFieldName)
+		if !ok {
+			err = errors.WithMessagef(ErrNotFoundFieldName, "correlation outer name:%s", c.OuterFieldName)
+			return nil, err
+		}
+		outer = outerField
+	}
+	innerColumn := goqu.I(fmt.Sprintf("%s.%s", inner.BaseName(), innerDBName))
+	return innerColumn.Eq(goqu.I(outer.DBColumnName().FullName())), nil
+}
+
+func subQueryWithCorrelations(subQuery SubQueryI, correlations []Correlation, f *Field, fs ...*Field) (ds *goqu.SelectDataset, err error) {
+	ds, err = subQuery.SubQuery()
+	if err != nil {
+		return nil, err
+	}
+	for _, correlation := range correlations {
+		expression, err := correlation.expression(subQuery.GetTable(), f, fs...)
+		if err != nil {
+			return nil, err
+		}
+		ds = ds.Where(expression)
+	}
+	return ds, nil
+}
+
+// ValueFnWhereInSubQuery 生成 col in (select ...) 条件,字段值不参与条件,子查询需只查询一列
+func ValueFnWhereInSubQuery(subQuery SubQueryI) ValueFn {
+	return ValueFn{
+		Fn: func(in any, f *Field, fs ...*Field) (any, error) {
+			ds, err := subQuery.SubQuery()
+			if err != nil {
+				return nil, err
+			}
+			return goqu.L("? IN ?", goqu.I(f.DBColumnName().FullName()), ds), nil // 使用In(ds) 会生成 in ((select ...)) 双层括号,mysql 会当作标量子查询
+		},
+		Layer: Value_Layer_DBFormat,
+	}
+}
+
+// ValueFnWhereNotInSubQuery 生成 col not in (select ...) 条件
+func ValueFnWhereNotInSubQuery(subQuery SubQueryI) ValueFn {
+	return ValueFn{
+		Fn: func(in any, f *Field, fs ...*Field) (any, error) {
+			ds, err := subQuery.SubQuery()
+			if err != nil {
+				return nil, err
+			}
+			return goqu.L("? NOT IN ?", goqu.I(f.DBColumnName().FullName()), ds), nil
+		},
+		Layer: Value_Layer_DBFormat,
+	}
+}
+
+// ValueFnWhereExists 生成 exists (select ... where 子查询条件 and 关联条件) 条件,如 查询VIP客户的订单:
+// NewCustomerId(0).AppendWhereFn(ValueFnWhereExists(NewExistsBuilder(customerTable).AppendFields(NewLevel("vip")), Correlation{InnerFieldName: "id"}))
+func ValueFnWhereExists(subQuery SubQueryI, correlations ...Correlation) ValueFn {
+	return ValueFn{
+		Fn: func(in any, f *Field, fs ...*Field) (any, error) {
+			ds, err := subQueryWithCorrelations(subQuery, correlations, f, fs...)
+			if err != nil {
+				return nil, err
+			}
+			return goqu.L("EXISTS ?", ds), nil
+		},
+		Layer: Value_Layer_DBFormat,
+	}
+}
+
+// ValueFnWhereNotExists 生成 not exists (select ...) 条件
+func ValueFnWhereNotExists(subQuery SubQueryI, correlations ...Correlation) ValueFn {
+	return ValueFn{
+		Fn: func(in any, f *Field, fs ...*Field) (any, error) {
+			ds, err := subQueryWithCorrelations(subQuery, correlations, f, fs...)
+			if err != nil {
+				return nil, err
+			}
+			return goqu.L("NOT EXISTS ?", ds), nil
+		},
+		Layer: Value_Layer_DBFormat,
+	}
+}
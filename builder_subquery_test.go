@@ -0,0 +1,64 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func NewSubQueryLevel(level string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(level, "level", "客户等级", 32).AppendWhereFn(sqlbuilder.ValueFnForward)
+}
+
+var subQueryCustomerTable = sqlbuilder.NewTableConfig("customer").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewSubQueryCustomerId)),
+	sqlbuilder.NewColumn("level", sqlbuilder.GetField(NewSubQueryLevel)),
+).AddIndexs(fkPrimaryIndex)
+
+var subQueryOrderTable = sqlbuilder.NewTableConfig("customer_order").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("customer_id", sqlbuilder.GetField(NewSubQueryCustomerId)),
+).AddIndexs(fkPrimaryIndex)
+
+func TestSubQuery(t *testing.T) {
+	db := sqlbuildertest.New(t, subQueryCustomerTable, subQueryOrderTable)
+	db.InsertFixtures(sqlbuildertest.Fixtures{
+		"customer":       {{"id": 1, "level": "vip"}, {"id": 2, "level": "normal"}, {"id": 3, "level": "vip"}},
+		"customer_order": {{"id": 10, "customer_id": 1}, {"id": 11, "customer_id": 2}, {"id": 12, "customer_id": 3}, {"id": 13, "customer_id": 3}},
+	})
+	customerTable, orderTable := db.Table("customer"), db.Table("customer_order")
+	t.Run("in", func(t *testing.T) {
+		vipIds := sqlbuilder.NewListBuilder(customerTable).AppendFields(NewSubQueryLevel("vip"), NewSubQueryCustomerId(0).SetSelectColumns("id"))
+		ids := make([]int, 0)
+		err := sqlbuilder.NewListBuilder(orderTable).AppendFields(
+			NewSubQueryCustomerId(0).AppendWhereFn(sqlbuilder.ValueFnWhereInSubQuery(vipIds)),
+			NewIterateUserId(0).SetSelectColumns("id"),
+		).List(&ids)
+		require.NoError(t, err)
+		require.Equal(t, []int{10, 12, 13}, ids)
+	})
+	t.Run("not exists", func(t *testing.T) {
+		vip := sqlbuilder.NewExistsBuilder(customerTable).AppendFields(NewSubQueryLevel("vip"))
+		ids := make([]int, 0)
+		err := sqlbuilder.NewListBuilder(orderTable).AppendFields(
+			NewSubQueryCustomerId(0).AppendWhereFn(sqlbuilder.ValueFnWhereNotExists(vip, sqlbuilder.Correlation{InnerFieldName: "customerId"})),
+			NewIterateUserId(0).SetSelectColumns("id"),
+		).List(&ids)
+		require.NoError(t, err)
+		require.Equal(t, []int{11}, ids)
+	})
+	t.Run("mysql in without order limit", func(t *testing.T) {
+		handler := sqlbuilder.NewDryRunHandler(sqlbuilder.Driver_mysql)
+		vipIds := sqlbuilder.NewListBuilder(subQueryCustomerTable.WithHandler(handler)).AppendFields(
+			NewSubQueryLevel("vip"), NewSubQueryCustomerId(0).SetSelectColumns("id"),
+			NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(10).SetTag(sqlbuilder.Field_tag_pageSize),
+		)
+		sql, err := sqlbuilder.NewListBuilder(subQueryOrderTable.WithHandler(handler)).ToSQL(sqlbuilder.Fields{
+			NewSubQueryCustomerId(0).AppendWhereFn(sqlbuilder.ValueFnWhereInSubQuery(vipIds)),
+		})
+		require.NoError(t, err)
+		require.Contains(t, sql, "WHERE `customer_order`.`customer_id` IN (SELECT `id` FROM `customer` WHERE (`customer`.`level` = 'vip')) ORDER BY")
+	})
+}
@@ -1,9 +1,14 @@
 package sqlbuilder_test
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
 	"github.com/suifengpiao14/sqlbuilder"
 )
 
@@ -28,7 +33,75 @@ func TestMysqlRealEscapeString(t *testing.T) {
 
 }
 
+// FuzzSQLite3EscapeString 转义后的值在sqlite中还原,需与原值一致
+func FuzzSQLite3EscapeString(f *testing.F) {
+	for _, seed := range []string{"", "abc", "it's", `a\'b`, `\`, `''`, "\n\r", `"quoted"`, "中文'\\", "\x1a"} {
+		f.Add(seed)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(f, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	driver := sqlbuilder.Driver_sqlite3
+	f.Fuzz(func(t *testing.T, val string) {
+		if strings.ContainsRune(val, 0) { // sqlite 字面量不支持 NUL
+			t.Skip()
+		}
+		// 字面量
+		var got string
+		err := db.QueryRow(fmt.Sprintf("select '%s'", driver.EscapeString(val))).Scan(&got)
+		require.NoError(t, err)
+		require.Equal(t, val, got)
+
+		// 条件表达式
+		whereSql := sqlbuilder.Expression2StringWithDriver(driver, goqu.L("?", val).Eq(val))
+		whereSql = strings.TrimPrefix(whereSql, "SELECT * ")
+		var count int
+		err = db.QueryRow(fmt.Sprintf("select count(*) %s", whereSql)).Scan(&count)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		// DDL 默认值
+		ddl := sqlbuilder.Column2DDLSQLite(sqlbuilder.ColumnConfig{DbName: "val", Type: "string", Default: val})
+		_, err = db.Exec(fmt.Sprintf("create table escape_fuzz (id integer primary key, %s)", ddl))
+		require.NoError(t, err)
+		defer db.Exec("drop table escape_fuzz")
+		_, err = db.Exec("insert into escape_fuzz (id) values (1)")
+		require.NoError(t, err)
+		err = db.QueryRow("select val from escape_fuzz").Scan(&got)
+		require.NoError(t, err)
+		require.Equal(t, val, got)
+	})
+}
+
 func TestNilSlice(t *testing.T) {
 	var a []string
 	fmt.Println(len(a))
 }
+
+func TestRegisterEscaperConcurrent(t *testing.T) {
+	driver := sqlbuilder.Driver("escaper_test")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sqlbuilder.RegisterEscaper(driver, func(val string) string { return strings.ReplaceAll(val, "'", "''") })
+		}()
+		go func() {
+			defer wg.Done()
+			_ = driver.EscapeString("it's")
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, "it''s", driver.EscapeString("it's"))
+}
+
+func TestExpression2StringWithDriverEscaper(t *testing.T) {
+	driver := sqlbuilder.Driver("escaper_expression_test")
+	expressions := []goqu.Expression{goqu.C("name").Eq("it's"), goqu.C("id").In(1, 2), goqu.L("? = '?'", "a")}
+	require.Equal(t, `SELECT * WHERE (("name" = 'it''s') AND ("id" IN (1, 2)) AND 'a' = '?')`, sqlbuilder.Expression2StringWithDriver(driver, expressions...)) // 未注册时使用 goqu 方言转义
+
+	sqlbuilder.RegisterEscaper(driver, func(val string) string { return "[" + strings.ReplaceAll(val, "'", "''") + "]" })
+	require.Equal(t, `SELECT * WHERE (("name" = '[it''s]') AND ("id" IN (1, 2)) AND '[a]' = '?')`, sqlbuilder.Expression2StringWithDriver(driver, expressions...))
+}
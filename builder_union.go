@@ -0,0 +1,258 @@
+package sqlbuilder
+
+import (
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// UnionParam 合并多个列结构相同的查询(union all),外层统一排序、分页,常用于分表查询
+// 子查询的where条件来自各自的ListParam,外层排序、分页来自UnionParam的Fields
+type UnionParam struct {
+	listParams []*ListParam
+	distinct   bool // 使用 union 去重,默认 union all
+	builderFns SelectBuilderFns
+	SQLParam[UnionParam]
+}
+
+func NewUnionBuilder(tableConfig TableConfig, listParams ...*ListParam) *UnionParam {
+	p := &UnionParam{}
+	p.SQLParam = NewSQLParam(p, tableConfig)
+	p.listParams = listParams
+	return p
+}
+
+func (p *UnionParam) AppendListParams(listParams ...*ListParam) *UnionParam {
+	p.listParams = append(p.listParams, listParams...)
+	return p
+}
+
+func (p *UnionParam) WithDistinct(distinct bool) *UnionParam {
+	p.distinct = distinct
+	return p
+}
+
+// WithBuilderFns 配置外层sql构建器
+func (p *UnionParam) WithBuilderFns(builderFns ...SelectBuilderFn) *UnionParam {
+	if len(p.builderFns) == 0 {
+		p.builderFns = SelectBuilderFns{}
+	}
+	p.builderFns = append(p.builderFns, builderFns...)
+	return p
+}
+
+type CustomFnUnionParam = CustomFn[UnionParam]
+type CustomFnUnionParams = CustomFns[UnionParam]
+
+func (p *UnionParam) ApplyCustomFn(customFns ...CustomFnUnionParam) *UnionParam {
+	p = CustomFns[UnionParam](customFns).Apply(p)
+	return p
+}
+
+// order 外层排序,子查询已别名为派生表,排序列需去掉表名前缀;未设置排序时按主键升序
+func (p UnionParam) order(fs Fields) (orderedExpressions []exp.OrderedExpression) {
+	orderedExpressions = make([]exp.OrderedExpression, 0)
+	for _, orderedExpression := range fs.Order() {
+		identifier, ok := orderedExpression.SortExpression().(exp.IdentifierExpression)
+		if !ok {
+			orderedExpressions = append(orderedExpressions, orderedExpression)
+			continue
+		}
+		direction := exp.DescSortDir
+		if orderedExpression.IsAsc() {
+			direction = exp.AscDir
+		}
+		column := goqu.I(cast.ToString(identifier.GetCol()))
+		orderedExpressions = append(orderedExpressions, exp.NewOrderedExpression(column, direction, orderedExpression.NullSortType()))
+	}
+	if len(orderedExpressions) > 0 {
+		return orderedExpressions
+	}
+	table := p.GetTable()
+	primary, exists := table.Indexs.GetPrimary()
+	if exists {
+		for _, columnName := range primary.ColumnNames(table) {
+			orderedExpressions = append(orderedExpressions, goqu.I(columnName).Asc())
+		}
+	}
+	return orderedExpressions
+}
+
+// makeUnionDataset 合并子查询,limit>0 时子查询按外层排序取前limit条,减少合并数据量
+func (p UnionParam) makeUnionDataset(order []exp.OrderedExpression, limit uint, subSelectFn SelectBuilderFn) (ds *goqu.SelectDataset, err error) {
+	if len(p.listParams) == 0 {
+		err = errors.Errorf("UnionParam.ToSQL(),table:%s list params required", p.GetTable().Name)
+		return nil, err
+	}
+	dialect := p.GetGoquDialect()
+	for i, listParam := range p.listParams {
+		subDs, err := listParam.makeSelectDataset(listParam._Fields)
+		if err != nil {
+			return nil, err
+		}
+		subDs = subDs.ClearOrder().ClearOffset().ClearLimit()
+		if limit > 0 {
+			subDs = subDs.Order(order...).Limit(limit)
+		}
+		if subSelectFn != nil {
+			subDs = subSelectFn(subDs)
+		}
+		subDs = dialect.From(subDs.As(fmt.Sprintf("t%d", i))) // 包裹一层,兼容sqlite 不支持union 子句中使用order by/limit
+		if ds == nil {
+			ds = subDs
+			continue
+		}
+		if p.distinct {
+			ds = ds.Union(subDs)
+			continue
+		}
+		ds = ds.UnionAll(subDs)
+	}
+	return ds, nil
+}
+
+func (p UnionParam) ToSQL(fs Fields) (sql string, err error) {
+	tableConfig := p.GetTable()
+	fs = fs.Builder(p.context, SCENE_SQL_SELECT, tableConfig, p.customFieldsFns)
+	errWithMsg := fmt.Sprintf("UnionParam.ToSQL(),table:%s", tableConfig.Name)
+	order := p.order(fs)
+	pageIndex, pageSize := fs.Pagination()
+	offset := pageIndex * pageSize
+	var subLimit uint
+	if pageSize > 0 && !p.distinct {
+		subLimit = offset + pageSize
+	}
+	unionDs, err := p.makeUnionDataset(order, subLimit, nil)
+	if err != nil {
+		err = errors.WithMessage(err, errWithMsg)
+		return "", err
+	}
+	ds := p.GetGoquDialect().From(unionDs.As("u")).Order(order...)
+	if pageSize > 0 {
+		ds = ds.Offset(offset).Limit(pageSize)
+	}
+	ds = p.builderFns.Apply(ds)
+	sql, _, err = ds.ToSQL()
+	if err != nil {
+		err = errors.WithMessage(err, errWithMsg)
+		return "", err
+	}
+	p.Log(sql)
+	return sql, nil
+}
+
+// TotalSQL 各子查询分别计数后求和
+func (p UnionParam) TotalSQL() (sql string, err error) {
+	errWithMsg := fmt.Sprintf("UnionParam.TotalSQL(),table:%s", p.GetTable().Name)
+	if p.distinct {
+		unionDs, err := p.makeUnionDataset(nil, 0, nil)
+		if err != nil {
+			err = errors.WithMessage(err, errWithMsg)
+			return "", err
+		}
+		sql, _, err = p.GetGoquDialect().From(unionDs.As("u")).Select(goqu.COUNT(goqu.Star()).As("count")).ToSQL()
+		if err != nil {
+			err = errors.WithMessage(err, errWithMsg)
+			return "", err
+		}
+		p.Log(sql)
+		return sql, nil
+	}
+	unionDs, err := p.makeUnionDataset(nil, 0, func(ds *goqu.SelectDataset) *goqu.SelectDataset {
+		return ds.ClearSelect().Select(goqu.COUNT(goqu.Star()).As("count"))
+	})
+	if err != nil {
+		err = errors.WithMessage(err, errWithMsg)
+		return "", err
+	}
+	sql, _, err = p.GetGoquDialect().From(unionDs.As("u")).Select(goqu.SUM(goqu.I("count")).As("count")).ToSQL()
+	if err != nil {
+		err = errors.WithMessage(err, errWithMsg)
+		return "", err
+	}
+	p.Log(sql)
+	return sql, nil
+}
+
+func (p UnionParam) List(result any) (err error) {
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "UnionParam.list",
+		Fn: func(ctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			err = p.list(*fsRef, result)
+			if err != nil {
+				return err
+			}
+			err = ctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p UnionParam) list(fs Fields, result any) (err error) {
+	sql, err := p.ToSQL(fs)
+	if err != nil {
+		return err
+	}
+	return p.GetHandlerWithInitTable().Query(p.context, sql, result)
+}
+
+func (p UnionParam) Pagination(result any) (total int64, err error) {
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(p._Table.modelMiddlewares...)
+	p.modelMiddlewarePool = p.modelMiddlewarePool.append(ModelMiddleware{
+		Name: "UnionParam.pagination",
+		Fn: func(ctx *ModelMiddlewareContext, fsRef *Fields) (err error) {
+			total, err = p.pagination(*fsRef, result)
+			if err != nil {
+				return err
+			}
+			*fsRef = fsRef.Append(NewTotal(total))
+			err = ctx.Next(fsRef)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	})
+	err = p.modelMiddlewarePool.run(p.GetTable(), p._Fields)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (p UnionParam) pagination(fs Fields, result any) (total int64, err error) {
+	index, size := fs.Pagination()
+	if index == 0 && size == 0 {
+		err = ErrPaginationSizeRequired
+		return 0, err
+	}
+	totalSql, err := p.TotalSQL()
+	if err != nil {
+		return 0, err
+	}
+	handler := p.GetHandlerWithInitTable()
+	total, err = handler.Count(totalSql)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	err = p.list(fs, result)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,77 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func TestUnion(t *testing.T) {
+	db := sqlbuildertest.New(t, newUserTableConfig("union_user_1"), newUserTableConfig("union_user_2"))
+	db.InsertFixtures(sqlbuildertest.Fixtures{
+		"union_user_1": {{"id": 1, "name": "a"}, {"id": 3, "name": "c"}, {"id": 5, "name": "e"}},
+		"union_user_2": {{"id": 2, "name": "b"}, {"id": 4, "name": "d"}, {"id": 6, "name": "f"}},
+	})
+	table := newUserTableConfig("union_user").WithHandler(db.Handler()).WithShardedTableNameFn(func(fs ...sqlbuilder.Field) (shardedTableNames []string) {
+		return []string{"union_user_1", "union_user_2"}
+	})
+
+	t.Run("sharded pagination", func(t *testing.T) {
+		order := NewIterateUserId(0).SetOrderFn(sqlbuilder.OrderFnDesc)
+		paginationBuilder := sqlbuilder.NewPaginationBuilder(table).AppendFields(order, NewPageIndex(1).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(2).SetTag(sqlbuilder.Field_tag_pageSize))
+		users := make([]iterateUser, 0)
+		total, err := sqlbuilder.NewShardedTablePaginationBuilder(*paginationBuilder).WithUnion(true).Pagination(&users)
+		require.NoError(t, err)
+		require.EqualValues(t, 6, total)
+		require.Equal(t, []iterateUser{{Id: 4, Name: "d"}, {Id: 3, Name: "c"}}, users)
+	})
+
+	t.Run("sharded merge", func(t *testing.T) {
+		order := NewIterateUserId(0).SetOrderFn(sqlbuilder.OrderFnDesc)
+		paginationBuilder := sqlbuilder.NewPaginationBuilder(table).AppendFields(order, NewPageIndex(1).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(2).SetTag(sqlbuilder.Field_tag_pageSize))
+		users := make([]map[string]any, 0)
+		total, err := sqlbuilder.NewShardedTablePaginationBuilder(*paginationBuilder).WithConcurrency(2).WithTotalCacheDuration(time.Minute).Pagination(&users)
+		require.NoError(t, err)
+		require.EqualValues(t, 6, total)
+		require.Len(t, users, 2)
+		require.EqualValues(t, 4, users[0]["id"])
+		require.EqualValues(t, 3, users[1]["id"])
+	})
+	t.Run("sharded merge struct tag differs from column", func(t *testing.T) {
+		db := sqlbuildertest.New(t, newUnionScoreTableConfig("union_score_1"), newUnionScoreTableConfig("union_score_2"))
+		db.InsertFixtures(sqlbuildertest.Fixtures{
+			"union_score_1": {{"id": 1, "user_score": 50}, {"id": 3, "user_score": 10}},
+			"union_score_2": {{"id": 2, "user_score": 40}, {"id": 4, "user_score": 20}},
+		})
+		scoreTable := newUnionScoreTableConfig("union_score").WithHandler(db.Handler()).WithShardedTableNameFn(func(fs ...sqlbuilder.Field) (shardedTableNames []string) {
+			return []string{"union_score_1", "union_score_2"}
+		})
+		order := NewUnionUserScore(0).SetOrderFn(sqlbuilder.OrderFnDesc).SetSelectColumns("id", goqu.I("user_score").As("userScore"))
+		paginationBuilder := sqlbuilder.NewPaginationBuilder(scoreTable).AppendFields(order, NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(3).SetTag(sqlbuilder.Field_tag_pageSize))
+		scores := make([]unionScore, 0)
+		_, err := sqlbuilder.NewShardedTablePaginationBuilder(*paginationBuilder).Pagination(&scores)
+		require.NoError(t, err)
+		require.Equal(t, []unionScore{{Id: 1, UserScore: 50}, {Id: 2, UserScore: 40}, {Id: 4, UserScore: 20}}, scores)
+	})
+}
+
+func NewUnionUserScore(score int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(score, "userScore", "用户积分", 0)
+}
+
+// unionScore db 标签为字段名 userScore,与列名 user_score 不同
+type unionScore struct {
+	Id        int `db:"id"`
+	UserScore int `db:"userScore"`
+}
+
+func newUnionScoreTableConfig(name string) sqlbuilder.TableConfig {
+	return sqlbuilder.NewTableConfig(name).AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("user_score", sqlbuilder.GetField(NewUnionUserScore)),
+	).AddIndexs(fkPrimaryIndex)
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cast"
-	"github.com/suifengpiao14/funcs"
 	"github.com/suifengpiao14/memorytable"
 )
 
@@ -57,7 +56,7 @@ func GenerateDDL(driver Driver, tableConfig TableConfig) (ddl string, err error)
 		sb.WriteString(strings.Join(columnDefs, ",\n"))
 		sb.WriteString("\n) ENGINE=InnoDB AUTO_INCREMENT=1  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci ")
 		if tableConfig.Comment != "" {
-			sb.WriteString(fmt.Sprintf(` COMMENT ="%s";`, tableConfig.Comment))
+			sb.WriteString(fmt.Sprintf(` COMMENT ="%s";`, driver.EscapeString(tableConfig.Comment)))
 
 		}
 	case Driver_sqlite3, _Driver_sqlite:
@@ -115,6 +114,15 @@ func MakeColumnsAndIndexs(driver Driver, table TableConfig) (lines []string, err
 				arr = append(arr, ddl)
 			}
 		}
+		for _, fk := range table.ForeignKeys {
+			ddl, err := ForeignKey2DDL(fk, table)
+			if err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(ddl) != "" {
+				arr = append(arr, ddl)
+			}
+		}
 
 	case Driver_sqlite3, _Driver_sqlite:
 		for _, col := range cols {
@@ -133,6 +141,15 @@ func MakeColumnsAndIndexs(driver Driver, table TableConfig) (lines []string, err
 				arr = append(arr, ddl)
 			}
 		}
+		for _, fk := range table.ForeignKeys {
+			ddl, err := ForeignKey2DDL(fk, table)
+			if err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(ddl) != "" {
+				arr = append(arr, ddl)
+			}
+		}
 	default:
 		err := errors.Errorf("unsport driver:%s", string(driver))
 		return nil, err
@@ -153,7 +170,7 @@ func Column2DDLSQLite(col ColumnConfig) (ddl string) {
 		colDef += " NOT NULL"
 	}
 	if col.Default != nil {
-		colDef += " DEFAULT " + escapeDefault(col.Default)
+		colDef += " DEFAULT " + escapeDefault(Driver_sqlite3, col.Default)
 	}
 	return colDef
 }
@@ -223,6 +240,33 @@ func Index2DDLMysql(index Index, table TableConfig) (ddl string) {
 	return ddl
 }
 
+// ForeignKey2DDL 外键约束定义,mysql、sqlite 语法一致
+func ForeignKey2DDL(fk ForeignKey, table TableConfig) (ddl string, err error) {
+	columnNames := fk.GetColumnNames(table)
+	refColumnNames, err := fk.GetRefColumnNames()
+	if err != nil {
+		return "", err
+	}
+	if len(columnNames) == 0 || len(columnNames) != len(refColumnNames) {
+		return "", nil
+	}
+	quote := func(names []string) string {
+		escaped := make([]string, 0, len(names))
+		for _, name := range names {
+			escaped = append(escaped, fmt.Sprintf("`%s`", name))
+		}
+		return strings.Join(escaped, ",")
+	}
+	ddl = fmt.Sprintf("  CONSTRAINT `%s` FOREIGN KEY (%s) REFERENCES `%s` (%s)", fk.ForeignKeyName(table), quote(columnNames), fk.RefTable.DBName.BaseName(), quote(refColumnNames))
+	if fk.OnDelete != ForeignKeyAction_default {
+		ddl = fmt.Sprintf("%s ON DELETE %s", ddl, fk.OnDelete)
+	}
+	if fk.OnUpdate != ForeignKeyAction_default {
+		ddl = fmt.Sprintf("%s ON UPDATE %s", ddl, fk.OnUpdate)
+	}
+	return ddl, nil
+}
+
 func Column2DDLMysql(col ColumnConfig) (ddl string) {
 	if col.Enums != nil {
 		col.Type = SchemaType(col.Enums.Type())
@@ -242,7 +286,7 @@ func Column2DDLMysql(col ColumnConfig) (ddl string) {
 	notNil := ""
 	comment := ""
 	if col.Comment != "" {
-		com := funcs.Addslashes(col.Comment)
+		com := Driver_mysql.EscapeString(col.Comment)
 		comment = fmt.Sprintf(`COMMENT "%s"`, com)
 	}
 	defaul := col.Default
@@ -253,7 +297,7 @@ func Column2DDLMysql(col ColumnConfig) (ddl string) {
 		if col.Length == 0 {
 			col.Length = 255
 		}
-		defaul = fmt.Sprintf(`"%s"`, cast.ToString(defaul)) // 增加引号
+		defaul = fmt.Sprintf(`"%s"`, Driver_mysql.EscapeString(cast.ToString(defaul))) // 增加引号
 		tr := TypeReflectsString.GetByUpperLimitWithDefault(col.Length)
 		if tr != nil {
 			typ = tr.DBType
@@ -390,10 +434,11 @@ var TypeReflectsInt = TypeReflects[int]{
 	{UpperLimit: Int_maximum_bigint, DBType: "bigint", Size: 11},
 }
 
-func escapeDefault(val any) string {
+// escapeDefault 默认值转为sql字面量,字符串按驱动转义
+func escapeDefault(driver Driver, val any) string {
 	switch v := val.(type) {
 	case string:
-		return fmt.Sprintf("'%s'", v)
+		return fmt.Sprintf("'%s'", driver.EscapeString(v))
 	case nil:
 		return "NULL"
 	default:
@@ -45,3 +45,9 @@ func TestGenerateDDL(t *testing.T) {
 func TestCrateTable(t *testing.T) {
 	table.GetHandlerWithInitTable()
 }
+
+func TestColumnWithNullable(t *testing.T) {
+	col := sqlbuilder.NewColumn("order_id", sqlbuilder.GetField(NewOrderId))
+	require.NotContains(t, sqlbuilder.Column2DDLSQLite(col.WithNullable(true)), "NOT NULL")
+	require.Contains(t, sqlbuilder.Column2DDLSQLite(col.WithNullable(false)), "NOT NULL")
+}
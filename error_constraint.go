@@ -0,0 +1,224 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// 数据库约束错误,由数据库返回的错误翻译而来,可通过 errors.Is 判断,通过 errors.As 获取 *ConstraintError 得到索引、列信息
+var (
+	ErrDuplicateKey = errors.New("duplicate key")         // 主键、唯一索引冲突
+	ErrForeignKey   = errors.New("foreign key violation") // 外键约束
+	ErrNotNull      = errors.New("not null violation")    // 非空约束
+	ErrDataTooLong  = errors.New("data too long")         // 数据超出列长度
+)
+
+// ConstraintError 数据库约束错误,Index、Column 为匹配到的表配置,未匹配时为空
+type ConstraintError struct {
+	Kind         error // ErrDuplicateKey、ErrForeignKey、ErrNotNull、ErrDataTooLong
+	Table        string
+	Index        *Index        // 唯一索引冲突时的索引
+	IndexColumns []string      // 唯一索引冲突时的索引列
+	Column       *ColumnConfig // 非空、长度超限时的列
+	ForeignKey   *ForeignKey   // 外键约束时匹配到的外键
+	Err          error         // 数据库原始错误
+}
+
+func (e *ConstraintError) Error() string {
+	target := ""
+	switch {
+	case e.Index != nil:
+		target = fmt.Sprintf(",index:%s", e.indexName())
+	case e.Column != nil:
+		target = fmt.Sprintf(",column:%s", e.Column.DbName)
+	case e.ForeignKey != nil:
+		target = fmt.Sprintf(",references:%s", e.ForeignKey.RefTable.Name)
+	}
+	return fmt.Sprintf("%s,table:%s%s: %s", e.Kind.Error(), e.Table, target, e.Err.Error())
+}
+
+func (e *ConstraintError) indexName() string {
+	if e.Index.IsPrimary {
+		return "PRIMARY"
+	}
+	return strings.Join(e.IndexColumns, ",")
+}
+
+func (e *ConstraintError) withIndex(t TableConfig, index *Index) {
+	if index == nil {
+		return
+	}
+	e.Index = index
+	e.IndexColumns = index.GetColumnNames(t)
+}
+
+// Unwrap 同时匹配约束类型和原始错误;唯一索引冲突兼容 ErrUnique
+func (e *ConstraintError) Unwrap() []error {
+	errs := []error{e.Kind, e.Err}
+	if e.Kind == ErrDuplicateKey {
+		errs = append(errs, ErrUnique)
+	}
+	return errs
+}
+
+const (
+	mysql_ER_DUP_ENTRY            = 1062
+	mysql_ER_BAD_NULL_ERROR       = 1048
+	mysql_ER_NO_DEFAULT_FOR_FIELD = 1364
+	mysql_ER_DATA_TOO_LONG        = 1406
+	mysql_ER_ROW_IS_REFERENCED    = 1451
+	mysql_ER_NO_REFERENCED_ROW    = 1452
+	mysql_ER_ROW_IS_REFERENCED_2  = 1217
+	mysql_ER_NO_REFERENCED_ROW_2  = 1216
+)
+
+var (
+	mysqlDupKeyRegexp   = regexp.MustCompile(`for key '([^']+)'`)
+	mysqlColumnRegexp   = regexp.MustCompile(`(?i)(?:column|field) '([^']+)'`)
+	mysqlFkRegexp       = regexp.MustCompile("CONSTRAINT `([^`]+)`")
+	sqliteColumnsRegexp = regexp.MustCompile(`constraint failed: (.+)$`)
+)
+
+// TranslateError 将数据库约束错误翻译为 *ConstraintError,非约束错误原样返回
+func (t TableConfig) TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var constraintErr *ConstraintError
+	if errors.As(err, &constraintErr) { // 已翻译
+		if e, ok := err.(*ConstraintError); ok && e.Table == "" { // 未关联表(如回放还原的错误),补充表名
+			translated := *e
+			translated.Table = t.Name
+			return &translated
+		}
+		return err
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return t.translateMysqlError(mysqlErr, err)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return t.translateSQLite3Error(sqliteErr, err)
+	}
+	return err
+}
+
+func (t TableConfig) translateMysqlError(mysqlErr *mysql.MySQLError, err error) error {
+	constraintErr := &ConstraintError{Table: t.Name, Err: err}
+	switch mysqlErr.Number {
+	case mysql_ER_DUP_ENTRY:
+		constraintErr.Kind = ErrDuplicateKey
+		if m := mysqlDupKeyRegexp.FindStringSubmatch(mysqlErr.Message); m != nil {
+			keyName := m[1]
+			if i := strings.LastIndex(keyName, "."); i > -1 { // mysql8 格式为 table.key
+				keyName = keyName[i+1:]
+			}
+			constraintErr.withIndex(t, t.getIndexByMysqlKeyName(keyName))
+		}
+	case mysql_ER_ROW_IS_REFERENCED, mysql_ER_NO_REFERENCED_ROW, mysql_ER_ROW_IS_REFERENCED_2, mysql_ER_NO_REFERENCED_ROW_2:
+		constraintErr.Kind = ErrForeignKey
+		if m := mysqlFkRegexp.FindStringSubmatch(mysqlErr.Message); m != nil { // 删除被引用记录时外键属于引用方表,通常匹配不到
+			constraintErr.ForeignKey, _ = t.ForeignKeys.GetByName(t, m[1])
+		}
+	case mysql_ER_BAD_NULL_ERROR, mysql_ER_NO_DEFAULT_FOR_FIELD:
+		constraintErr.Kind = ErrNotNull
+		constraintErr.Column = t.getColumnByErrorMessage(mysqlErr.Message)
+	case mysql_ER_DATA_TOO_LONG:
+		constraintErr.Kind = ErrDataTooLong
+		constraintErr.Column = t.getColumnByErrorMessage(mysqlErr.Message)
+	default:
+		return err
+	}
+	return constraintErr
+}
+
+func (t TableConfig) translateSQLite3Error(sqliteErr sqlite3.Error, err error) error {
+	constraintErr := &ConstraintError{Table: t.Name, Err: err}
+	columnNames := make([]string, 0)
+	if m := sqliteColumnsRegexp.FindStringSubmatch(sqliteErr.Error()); m != nil { // 格式: UNIQUE constraint failed: table.a, table.b
+		for _, name := range strings.Split(m[1], ",") {
+			name = strings.TrimSpace(name)
+			if i := strings.LastIndex(name, "."); i > -1 {
+				name = name[i+1:]
+			}
+			columnNames = append(columnNames, name)
+		}
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		constraintErr.Kind = ErrDuplicateKey
+		constraintErr.withIndex(t, t.getIndexByColumnNames(columnNames, sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey))
+	case sqlite3.ErrConstraintForeignKey:
+		constraintErr.Kind = ErrForeignKey
+	case sqlite3.ErrConstraintNotNull:
+		constraintErr.Kind = ErrNotNull
+		if len(columnNames) > 0 {
+			if col, ok := t.Columns.GetByDbName(columnNames[0]); ok {
+				constraintErr.Column = &col
+			}
+		}
+	default:
+		return err
+	}
+	return constraintErr
+}
+
+// getIndexByMysqlKeyName 按 DDL 生成规则(PRIMARY、uk_列名)匹配索引
+func (t TableConfig) getIndexByMysqlKeyName(keyName string) *Index {
+	for _, index := range t.Indexs {
+		if strings.EqualFold(keyName, "PRIMARY") && index.IsPrimary {
+			return &index
+		}
+		if index.ColumnNames == nil {
+			continue
+		}
+		columnNames := index.GetColumnNames(t)
+		name := strings.Join(columnNames, "_")
+		if strings.EqualFold(keyName, name) || strings.EqualFold(keyName, "uk_"+name) {
+			return &index
+		}
+	}
+	return nil
+}
+
+// getIndexByColumnNames 按索引列匹配唯一索引、主键
+func (t TableConfig) getIndexByColumnNames(columnNames []string, isPrimary bool) *Index {
+	if len(columnNames) == 0 && isPrimary {
+		primary, ok := t.Indexs.GetPrimary()
+		if ok {
+			return primary
+		}
+		return nil
+	}
+	slices.Sort(columnNames)
+	for _, index := range t.Indexs {
+		if (!index.IsPrimary && !index.Unique) || index.ColumnNames == nil {
+			continue
+		}
+		indexColumnNames := slices.Clone(index.GetColumnNames(t))
+		slices.Sort(indexColumnNames)
+		if slices.Equal(indexColumnNames, columnNames) {
+			return &index
+		}
+	}
+	return nil
+}
+
+func (t TableConfig) getColumnByErrorMessage(message string) *ColumnConfig {
+	m := mysqlColumnRegexp.FindStringSubmatch(message)
+	if m == nil {
+		return nil
+	}
+	col, ok := t.Columns.GetByDbName(m[1])
+	if !ok {
+		return nil
+	}
+	return &col
+}
@@ -0,0 +1,88 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+var constraintUserTable = sqlbuilder.NewTableConfig("constraint_user").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+).AddIndexs(fkPrimaryIndex, sqlbuilder.Index{
+	Unique: true,
+	ColumnNames: func(table sqlbuilder.TableConfig) (columnNames []string) {
+		return []string{"name"}
+	},
+})
+
+func newConstraintTable(t *testing.T) sqlbuilder.TableConfig {
+	db := sqlbuildertest.New(t, constraintUserTable)
+	db.InsertFixtures(sqlbuildertest.Fixtures{"constraint_user": {{"id": 1, "name": "alice"}}})
+	return db.Table("constraint_user")
+}
+
+func TestConstraintError(t *testing.T) {
+	ctx := context.Background()
+	table := newConstraintTable(t)
+	repository := table.Repository()
+
+	t.Run("insert duplicate unique", func(t *testing.T) {
+		err := repository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(2), NewIterateUserName("alice")})
+		require.ErrorIs(t, err, sqlbuilder.ErrDuplicateKey)
+		require.ErrorIs(t, err, sqlbuilder.ErrUnique)
+		var constraintErr *sqlbuilder.ConstraintError
+		require.ErrorAs(t, err, &constraintErr)
+		require.NotNil(t, constraintErr.Index)
+		require.True(t, constraintErr.Index.Unique)
+		require.Equal(t, []string{"name"}, constraintErr.IndexColumns)
+	})
+	t.Run("insert duplicate primary", func(t *testing.T) {
+		_, _, err := sqlbuilder.NewInsertBuilder(table).AppendFields(NewIterateUserId(1), NewIterateUserName("bob")).Insert()
+		var constraintErr *sqlbuilder.ConstraintError
+		require.ErrorAs(t, err, &constraintErr)
+		require.True(t, constraintErr.Index.IsPrimary)
+	})
+	t.Run("update duplicate", func(t *testing.T) {
+		require.NoError(t, repository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(3), NewIterateUserName("carol")}))
+		_, err := sqlbuilder.NewUpdateBuilder(table).AppendFields(NewIterateUserId(3).AppendWhereFn(sqlbuilder.ValueFnForward), NewIterateUserName("alice")).Update()
+		require.ErrorIs(t, err, sqlbuilder.ErrDuplicateKey)
+	})
+
+	t.Run("skip unique pre-check", func(t *testing.T) {
+		fs := sqlbuilder.Fields{NewIterateUserId(4), NewIterateUserName("alice")}.SetTable(table)
+		err := table.CheckUniqueIndex(fs...)
+		require.ErrorIs(t, err, sqlbuilder.Error_UniqueIndexAlreadyExist) // exists 预检查
+
+		skipTable := table.WithSkipUniqueCheck(true)
+		require.NoError(t, skipTable.CheckUniqueIndex(fs...))
+		err = skipTable.Repository().Insert(ctx, fs)
+		require.ErrorIs(t, err, sqlbuilder.ErrDuplicateKey) // 由数据库唯一约束保证
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		err := table.TranslateError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'alice' for key 'constraint_user.uk_name'"})
+		var constraintErr *sqlbuilder.ConstraintError
+		require.ErrorAs(t, err, &constraintErr)
+		require.Equal(t, []string{"name"}, constraintErr.IndexColumns)
+
+		err = table.TranslateError(errors.WithMessage(&mysql.MySQLError{Number: 1406, Message: "Data too long for column 'name' at row 1"}, "insert"))
+		require.ErrorIs(t, err, sqlbuilder.ErrDataTooLong)
+		require.ErrorAs(t, err, &constraintErr)
+		require.Equal(t, "name", constraintErr.Column.DbName)
+
+		err = table.TranslateError(&mysql.MySQLError{Number: 1048, Message: "Column 'name' cannot be null"})
+		require.ErrorIs(t, err, sqlbuilder.ErrNotNull)
+
+		err = table.TranslateError(&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"})
+		require.ErrorIs(t, err, sqlbuilder.ErrForeignKey)
+
+		other := &mysql.MySQLError{Number: 1064}
+		require.Equal(t, error(other), table.TranslateError(other))
+	})
+}
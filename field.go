@@ -648,6 +648,9 @@ const (
 	//Field_tag_update_limit = "updateLimit" // 标记为updateLimit列 ,使用PageSize标记，减少比必要预先定义
 
 	Field_tag_CanWriteWhenDeleted = "CanWriteWhenDeleted" // 标记为删除场景下，可以更新数据库字段（如操作人 ，Field_name_deletedAt 自带该标签功能）
+
+	Field_tag_groupBy          = "groupBy"    // 标记为聚合查询分组列
+	field_tag_aggregate_prefix = "aggregate:" // 聚合函数标签前缀,如 aggregate:sum
 )
 
 // func (f *Field) SetDDLsequence(DDLsequence int) *Field {
@@ -1038,6 +1041,27 @@ func (f *Field) GetTags() Tags {
 	return f.tags
 }
 
+// SetGroupBy 标记为聚合查询的分组维度列
+func (f *Field) SetGroupBy() *Field {
+	f.tags.Append(Field_tag_groupBy)
+	return f
+}
+
+// SetAggregate 标记为聚合查询的度量列,查询结果使用Name作为列别名,WhereFns 生成 having 条件
+func (f *Field) SetAggregate(aggregateFn AggregateFn) *Field {
+	f.tags.Append(aggregateFn.tag())
+	return f
+}
+
+func (f *Field) GetAggregate() (aggregateFn AggregateFn, ok bool) {
+	for _, tag := range f.tags {
+		if after, found := strings.CutPrefix(tag, field_tag_aggregate_prefix); found {
+			return AggregateFn(after), true
+		}
+	}
+	return "", false
+}
+
 // Deprecated use AppendEnums 代替 一个一个的定义不方便，直接定义数组即可
 func (f *Field) AppendEnum(enums ...Enum) *Field {
 	if f.Schema == nil {
@@ -1534,6 +1558,18 @@ func IsErrorValueNil(err error) bool {
 	return errors.Is(err, ErrValueNil)
 }
 
+// newErrorField 链式调用无法返回的错误,生成 where 条件时返回
+func newErrorField(name string, err error) *Field {
+	f := &Field{Name: name}
+	f.WhereFns.Append(ValueFn{
+		Layer: Value_Layer_DBFormat,
+		Fn: func(_ any, _ *Field, _ ...*Field) (any, error) {
+			return nil, err
+		},
+	})
+	return f
+}
+
 // ValueFnArgEmptyStr2NilExceptFields 将空字符串值转换为nil值时排除的字段,常见的有 deleted_at 字段,空置代表正常
 //var ValueFnArgEmptyStr2NilExceptFields = Fields{}
 
@@ -1624,6 +1660,9 @@ func (f Field) getValue(layers []Layer, fs ...*Field) (value any, err error) {
 	if f.value == nil { // 空值不处理，直接返回
 		return nil, ErrValueNil
 	}
+	if IsNullValue(f.value) { // 显式 NULL 不经过 ValueFns(格式化会将其转换为零值),写入时由 validateNull 校验
+		return Null, nil
+	}
 	val := reflect.Indirect(reflect.ValueOf(f.value)).Interface() // f.value 基本上是指针，此处转为值类型，方便后续操作，也是兼容历史
 	if f.ValueFns == nil {                                        // 防止空指针
 		return val, nil
@@ -1791,6 +1830,13 @@ func (f1 Field) Data(layers []Layer, fs ...*Field) (data any, err error) {
 	if f.scene.Is(SCENE_SQL_UPDATE) && f.Schema != nil && f.Schema.ShieldUpdate { // 当前为更新场景，并且设置屏蔽更新，则返回nil
 		return nil, nil
 	}
+	if IsNullValue(val) {
+		err = f.validateNull()
+		if err != nil {
+			return nil, err
+		}
+		val = nil // 写入 SQL NULL
+	}
 	// 此处多次实践，发现基本的转义，在sql中已经完成，基本不用再进行转义处理了，除非特殊场景需要手动转义，例如：json序列化内嵌套序列化，情况比较有限，不作为默认处理
 	// if valStr, ok := val.(string); ok {
 	// 	val = Dialect.EscapeString(valStr)
@@ -1810,6 +1856,9 @@ func (f Field) Where(fs ...*Field) (expressions Expressions, err error) {
 		return nil, nil
 	}
 	dbName := f.DBColumnName().FullName()
+	if IsNullValue(val) {
+		return ConcatExpression(goqu.Ex{dbName: nil}), nil // IS NULL
+	}
 	if ex, ok := TryParseExpressions(dbName, val); ok {
 		return ex, nil
 	}
@@ -2158,6 +2207,10 @@ func (fs Fields) Validate() (err error) {
 		if err != nil {
 			return err
 		}
+		err = f.validateNull()
+		if err != nil {
+			return err
+		}
 	}
 	return err
 }
@@ -2880,13 +2933,55 @@ func TryParseExpressions(field string, value any) (expressions Expressions, ok b
 
 // Deprecated: 请使用Expression2StringWithDriver
 func Expression2String(expressions ...goqu.Expression) string {
-	sql, _, _ := Dialect.DialectWrapper().Select().Where(expressions...).ToSQL()
-	return sql
+	return Expression2StringWithDriver(Driver(Dialect.Dialect()), expressions...)
 }
 
+// Expression2StringWithDriver 生成条件语句,字符串字面量使用驱动注册的转义函数(RegisterEscaper)转义
 func Expression2StringWithDriver(driver Driver, expressions ...goqu.Expression) string {
-	sql, _, _ := driver.GoquDialect().Select().Where(expressions...).ToSQL()
-	return sql
+	sql, args, _ := driver.GoquDialect().Select().Where(expressions...).Prepared(true).ToSQL()
+	return interpolateSQL(driver, sql, args)
+}
+
+// interpolateSQL 将预处理 sql 中引号外的 ? 占位符依次替换为参数字面量
+func interpolateSQL(driver Driver, sql string, args []any) string {
+	if len(args) == 0 {
+		return sql
+	}
+	var w strings.Builder
+	var quote byte
+	argIndex := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?' && argIndex < len(args):
+			w.WriteString(driverLiteral(driver, args[argIndex]))
+			argIndex++
+			continue
+		}
+		w.WriteByte(c)
+	}
+	return w.String()
+}
+
+// driverLiteral 字符串按驱动注册的转义函数转义后加单引号,其它类型及未注册转义函数的驱动由 goqu 方言生成字面量
+func driverLiteral(driver Driver, val any) string {
+	if escaper, ok := driver.Escaper(); ok {
+		switch v := val.(type) {
+		case []byte:
+			return fmt.Sprintf("'%s'", escaper(string(v)))
+		}
+		if rv := reflect.ValueOf(val); rv.Kind() == reflect.String {
+			return fmt.Sprintf("'%s'", escaper(rv.String()))
+		}
+	}
+	sql, _, _ := driver.GoquDialect().Select(goqu.V(val)).ToSQL()
+	return strings.TrimPrefix(sql, "SELECT ")
 }
 
 type FieldName2DBColumnNameFn func(fieldName string) (dbColumnName string)
@@ -3146,11 +3241,12 @@ func MakeColumnConfigFromStruct(m any, dbNameSource StructFieldSource) (columnCo
 }
 
 const (
-	FieldName_lastInsertId = "db_lastInsertId"
-	FieldName_rowsAffected = "db_rowsAffected"
-	FieldName_exists       = "db_exists"
-	FieldName_not_exists   = "db_not_exists"
-	FieldName_total        = "db_total"
+	FieldName_lastInsertId   = "db_lastInsertId"
+	FieldName_rowsAffected   = "db_rowsAffected"
+	FieldName_exists         = "db_exists"
+	FieldName_not_exists     = "db_not_exists"
+	FieldName_total          = "db_total"
+	FieldName_changedColumns = "db_changedColumns"
 )
 
 func NewLastInsertId(lastInsertId uint64) *Field {
@@ -3165,6 +3261,9 @@ func NewExists(exists bool) *Field {
 func NewNotExists(notExists bool) *Field {
 	return NewField(notExists).SetName(FieldName_not_exists).SetTitle("是否不存在")
 }
+func NewChangedColumns(changedColumns []string) *Field {
+	return NewField(changedColumns).SetName(FieldName_changedColumns).SetTitle("变更列")
+}
 func NewTotal(total int64) *Field {
 	return NewIntField(total, FieldName_total, "是否存在", 0)
 }
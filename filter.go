@@ -0,0 +1,354 @@
+package sqlbuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// ErrInvalidFilter 过滤条件格式错误、字段不在白名单、值类型不匹配
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// FilterOperator 过滤操作符
+type FilterOperator string
+
+const (
+	FilterOperator_eq      FilterOperator = "eq"
+	FilterOperator_neq     FilterOperator = "neq"
+	FilterOperator_gt      FilterOperator = "gt"
+	FilterOperator_gte     FilterOperator = "gte"
+	FilterOperator_lt      FilterOperator = "lt"
+	FilterOperator_lte     FilterOperator = "lte"
+	FilterOperator_in      FilterOperator = "in"      // 多个值逗号分隔
+	FilterOperator_notIn   FilterOperator = "notIn"   // 多个值逗号分隔
+	FilterOperator_like    FilterOperator = "like"    // 包含,值中 %、_ 按普通字符匹配;是否区分大小写取决于列排序规则(mysql _ci 排序规则、sqlite ASCII 字符不区分)
+	FilterOperator_between FilterOperator = "between" // 2个值逗号分隔,包含边界,其中一个为空时为 gte、lte
+	FilterOperator_isNull  FilterOperator = "isNull"  // 无值
+	FilterOperator_notNull FilterOperator = "notNull" // 无值
+)
+
+var filterOperators = []FilterOperator{
+	FilterOperator_eq, FilterOperator_neq, FilterOperator_gt, FilterOperator_gte, FilterOperator_lt, FilterOperator_lte,
+	FilterOperator_in, FilterOperator_notIn, FilterOperator_like, FilterOperator_between, FilterOperator_isNull, FilterOperator_notNull,
+}
+
+func parseFilterOperator(op string) (operator FilterOperator, err error) {
+	for _, operator := range filterOperators {
+		if strings.EqualFold(string(operator), op) {
+			return operator, nil
+		}
+	}
+	err = errors.WithMessagef(ErrInvalidFilter, "unsupported operator:%s", op)
+	return "", err
+}
+
+func (op FilterOperator) isList() bool {
+	return op == FilterOperator_in || op == FilterOperator_notIn || op == FilterOperator_between
+}
+
+func (op FilterOperator) withoutValue() bool {
+	return op == FilterOperator_isNull || op == FilterOperator_notNull
+}
+
+// Filter 单个过滤条件,Name 对应白名单 Field.Name
+type Filter struct {
+	Name     string         `json:"name"`
+	Operator FilterOperator `json:"operator"`
+	Value    any            `json:"value"`
+}
+
+type Filters []Filter
+
+// ParseFilters 解析紧凑格式过滤条件: 条件间分号分隔,每个条件为 字段:操作符:值,列表值逗号分隔,
+// 如 status:in:1,2;createdAt:between:2026-01-01,2026-02-01;name:like:abc;deletedAt:isNull
+func ParseFilters(s string) (filters Filters, err error) {
+	filters = make(Filters, 0)
+	for _, segment := range strings.Split(s, ";") {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+		parts := strings.SplitN(segment, ":", 3) // 值中可能包含冒号,如时间
+		if len(parts) < 2 {
+			err = errors.WithMessagef(ErrInvalidFilter, "filter:%s required format name:operator:value", segment)
+			return nil, err
+		}
+		operator, err := parseFilterOperator(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		filter := Filter{Name: parts[0], Operator: operator}
+		if len(parts) == 3 {
+			filter.Value = parts[2]
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// ParseFiltersJSON 解析 json 对象格式过滤条件,键为字段名,值为 {操作符:值} 对象,非对象值为 eq,
+// 如 {"status":{"in":[1,2]},"createdAt":{"between":["2026-01-01","2026-02-01"]},"name":{"like":"abc"},"id":3}
+func ParseFiltersJSON(b []byte) (filters Filters, err error) {
+	m := make(map[string]json.RawMessage)
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		err = errors.WithMessagef(ErrInvalidFilter, "json filter:%s", err.Error())
+		return nil, err
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names) // 保证生成sql稳定
+	filters = make(Filters, 0, len(names))
+	for _, name := range names {
+		var raw any
+		err = json.Unmarshal(m[name], &raw)
+		if err != nil {
+			return nil, err
+		}
+		ops, ok := raw.(map[string]any)
+		if !ok {
+			filters = append(filters, Filter{Name: name, Operator: FilterOperator_eq, Value: raw})
+			continue
+		}
+		opNames := make([]string, 0, len(ops))
+		for op := range ops {
+			opNames = append(opNames, op)
+		}
+		slices.Sort(opNames)
+		for _, op := range opNames {
+			operator, err := parseFilterOperator(op)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, Filter{Name: name, Operator: operator, Value: ops[op]})
+		}
+	}
+	return filters, nil
+}
+
+// Fields 按白名单生成 where 字段:字段不在白名单时报错,值按白名单字段 Schema 类型转换,并经过字段原有 ValueFns 及 Schema 校验(长度、枚举、最大值等)
+func (filters Filters) Fields(whitelist Fields) (fs Fields, err error) {
+	fs = make(Fields, 0, len(filters))
+	for _, filter := range filters {
+		f, err := filter.Field(whitelist)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+// Field 按白名单生成 where 字段,between 生成 gte、lte 的 and 分组字段
+func (filter Filter) Field(whitelist Fields) (f *Field, err error) {
+	allowed, ok := whitelist.GetByName(filter.Name)
+	if !ok {
+		err = errors.WithMessagef(ErrInvalidFilter, "field:%s not allowed", filter.Name)
+		return nil, err
+	}
+	value, err := filter.value(allowed)
+	if err != nil {
+		err = errors.WithMessagef(err, "filter field:%s operator:%s", filter.Name, filter.Operator)
+		return nil, err
+	}
+	if filter.Operator == FilterOperator_between {
+		bounds := value.([]any)
+		children := make(Fields, 0, 2)
+		for i, op := range []FilterOperator{FilterOperator_gte, FilterOperator_lte} {
+			if bounds[i] == nil { // 单边为空
+				continue
+			}
+			children = append(children, allowed.Copy().SetValue(bounds[i]).ResetWhereFn(op.whereFn()))
+		}
+		return NewAndField(children...), nil
+	}
+	f = allowed.Copy().SetValue(value).ResetWhereFn(filter.Operator.whereFn())
+	return f, nil
+}
+
+func (filter Filter) value(f *Field) (value any, err error) {
+	if filter.Operator.withoutValue() {
+		return nil, nil
+	}
+	if !filter.Operator.isList() {
+		if IsNil(filter.Value) || cast.ToString(filter.Value) == "" {
+			err = errors.WithMessage(ErrInvalidFilter, "value required")
+			return nil, err
+		}
+		return filterFormatValue(f, filter.Value)
+	}
+	var arr []any
+	switch v := filter.Value.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			arr = append(arr, strings.TrimSpace(s))
+		}
+	case []any:
+		arr = v
+	default:
+		arr = []any{v}
+	}
+	if filter.Operator == FilterOperator_between {
+		if len(arr) != 2 {
+			err = errors.WithMessagef(ErrInvalidFilter, "between required 2 values,got:%d", len(arr))
+			return nil, err
+		}
+		bounds := make([]any, 2)
+		for i, v := range arr {
+			if IsNil(v) || cast.ToString(v) == "" { // 单边为空
+				continue
+			}
+			bounds[i], err = filterFormatValue(f, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if bounds[0] == nil && bounds[1] == nil {
+			err = errors.WithMessage(ErrInvalidFilter, "between required at least one value")
+			return nil, err
+		}
+		return bounds, nil
+	}
+	var values reflect.Value
+	for _, v := range arr {
+		if IsNil(v) || cast.ToString(v) == "" {
+			continue
+		}
+		v, err = filterFormatValue(f, v)
+		if err != nil {
+			return nil, err
+		}
+		rv := reflect.ValueOf(v)
+		if !values.IsValid() {
+			values = reflect.MakeSlice(reflect.SliceOf(rv.Type()), 0, len(arr))
+		}
+		if rv.Type() != values.Type().Elem() {
+			err = errors.WithMessagef(ErrInvalidFilter, "values type mismatch,%s and %s", values.Type().Elem().String(), rv.Type().String())
+			return nil, err
+		}
+		values = reflect.Append(values, rv) // 使用具体类型切片,便于 Schema 校验、格式化
+	}
+	if !values.IsValid() {
+		err = errors.WithMessage(ErrInvalidFilter, "value required")
+		return nil, err
+	}
+	return values.Interface(), nil
+}
+
+// filterFormatValue 按字段 Schema 类型转换值,转换失败返回 ErrInvalidFilter
+func filterFormatValue(f *Field, v any) (value any, err error) {
+	value, err = convertBySchemaType(f, v)
+	if err != nil {
+		err = errors.WithMessage(ErrInvalidFilter, err.Error())
+		return nil, err
+	}
+	return value, nil
+}
+
+// convertBySchemaType 按字段 Schema 类型转换外部输入值(字符串、json 数字等),转换失败返回错误(不同于 FormatType 静默转换为零值)
+func convertBySchemaType(f *Field, v any) (value any, err error) {
+	if f.Schema == nil {
+		return v, nil
+	}
+	switch v.(type) {
+	case map[string]any, []any:
+		if f.Schema.Type == Schema_Type_int || f.Schema.Type == Schema_Type_string {
+			err = errors.Errorf("%s value:%v required scalar", f.Name, v)
+			return nil, err
+		}
+	}
+	switch f.Schema.Type {
+	case Schema_Type_int:
+		switch val := v.(type) {
+		case string:
+			value, err = strconv.Atoi(strings.TrimSpace(val)) // cast.ToIntE 按进制前缀解析字符串,如 "010" 为 8
+		case float64:
+			if val != math.Trunc(val) { // json 数字解析为 float64,cast.ToIntE 会截断小数
+				err = errors.Errorf("not integral")
+				break
+			}
+			value, err = cast.ToIntE(val)
+		default:
+			value, err = cast.ToIntE(v)
+		}
+		if err != nil {
+			err = errors.Errorf("%s value:%v required int", f.Name, v)
+			return nil, err
+		}
+		return value, nil
+	case Schema_Type_string:
+		value, err = cast.ToStringE(v)
+		if err != nil {
+			err = errors.Errorf("%s value:%v required string", f.Name, v)
+			return nil, err
+		}
+		return value, nil
+	}
+	return v, nil
+}
+
+func (op FilterOperator) whereFn() ValueFn {
+	compare := func(fn func(col exp.IdentifierExpression, in any) goqu.Expression) ValueFn {
+		return ValueFn{
+			Layer: Value_Layer_DBFormat,
+			Fn: func(in any, f *Field, fs ...*Field) (any, error) {
+				if IsNil(in) {
+					return nil, nil
+				}
+				return fn(goqu.I(f.DBColumnName().FullName()), in), nil
+			},
+		}
+	}
+	switch op {
+	case FilterOperator_neq:
+		return compare(func(col exp.IdentifierExpression, in any) goqu.Expression { return col.Neq(in) })
+	case FilterOperator_gt:
+		return compare(func(col exp.IdentifierExpression, in any) goqu.Expression { return col.Gt(in) })
+	case FilterOperator_gte:
+		return compare(func(col exp.IdentifierExpression, in any) goqu.Expression { return col.Gte(in) })
+	case FilterOperator_lt:
+		return compare(func(col exp.IdentifierExpression, in any) goqu.Expression { return col.Lt(in) })
+	case FilterOperator_lte:
+		return compare(func(col exp.IdentifierExpression, in any) goqu.Expression { return col.Lte(in) })
+	case FilterOperator_in:
+		return compare(func(col exp.IdentifierExpression, in any) goqu.Expression { return col.In(in) })
+	case FilterOperator_notIn:
+		return compare(func(col exp.IdentifierExpression, in any) goqu.Expression { return col.NotIn(in) })
+	case FilterOperator_like:
+		return filterLikeWhereFn
+	case FilterOperator_isNull:
+		return ValueFnIsNull
+	case FilterOperator_notNull:
+		return ValueFnIsNotNull
+	}
+	return ValueFnForward
+}
+
+// filterLikeEscape like 转义字符,不使用反斜杠,避免 mysql、sqlite 字符串字面量转义规则不同
+const filterLikeEscape = "!"
+
+var filterLikeReplacer = strings.NewReplacer(filterLikeEscape, filterLikeEscape+filterLikeEscape, "%", filterLikeEscape+"%", "_", filterLikeEscape+"_")
+
+// filterLikeWhereFn 包含匹配,转义值中的通配符 %、_
+var filterLikeWhereFn = ValueFn{
+	Layer: Value_Layer_DBFormat,
+	Fn: func(in any, f *Field, fs ...*Field) (any, error) {
+		str := cast.ToString(in)
+		if IsNil(in) || str == "" {
+			return nil, nil
+		}
+		pattern := "%" + filterLikeReplacer.Replace(str) + "%"
+		return goqu.L(fmt.Sprintf("? LIKE ? ESCAPE '%s'", filterLikeEscape), goqu.I(f.DBColumnName().FullName()), pattern), nil
+	},
+}
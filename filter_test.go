@@ -0,0 +1,120 @@
+package sqlbuilder_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+var filterUserTable = sqlbuilder.NewTableConfig("filter_user").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+).AddIndexs(fkPrimaryIndex)
+
+func TestFilters(t *testing.T) {
+	db := sqlbuildertest.New(t, filterUserTable)
+	rows := make([]map[string]any, 0)
+	for i := 1; i <= 6; i++ {
+		rows = append(rows, map[string]any{"id": i, "name": fmt.Sprintf("user%d", i)})
+	}
+	rows = append(rows, map[string]any{"id": 8, "name": "user_8%"}) // 含通配符
+	db.InsertFixtures(sqlbuildertest.Fixtures{"filter_user": rows})
+	db.InsertFixtures(sqlbuildertest.Fixtures{"filter_user": {{"id": 7}}}) // name 为 NULL
+	table := db.Table("filter_user")
+	whitelist := sqlbuilder.Fields{NewIterateUserId(0), NewIterateUserName("")}
+	listIds := func(t *testing.T, fs sqlbuilder.Fields) []int {
+		ids := make([]int, 0)
+		err := sqlbuilder.NewListBuilder(table).AppendFields(fs...).AppendFields(NewIterateUserId(0).SetSelectColumns("id").SetOrderFn(sqlbuilder.OrderFnAsc)).List(&ids)
+		require.NoError(t, err)
+		return ids
+	}
+
+	t.Run("compact", func(t *testing.T) {
+		filters, err := sqlbuilder.ParseFilters("id:in:1,2,3,5;name:like:user;id:neq:2")
+		require.NoError(t, err)
+		fs, err := filters.Fields(whitelist)
+		require.NoError(t, err)
+		sql, err := sqlbuilder.NewListBuilder(table).ToSQL(fs)
+		require.NoError(t, err)
+		require.Contains(t, sql, "WHERE ((`filter_user`.`id` IN (1, 2, 3, 5)) AND `filter_user`.`name` LIKE '%user%' ESCAPE '!' AND (`filter_user`.`id` != 2))")
+		require.Equal(t, []int{1, 3, 5}, listIds(t, fs))
+
+		filters, err = sqlbuilder.ParseFilters("id:between:2,4;id:notIn:3")
+		require.NoError(t, err)
+		fs, err = filters.Fields(whitelist)
+		require.NoError(t, err)
+		require.Equal(t, []int{2, 4}, listIds(t, fs))
+
+		filters, err = sqlbuilder.ParseFilters("id:between:,2")
+		require.NoError(t, err)
+		fs, err = filters.Fields(whitelist)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, listIds(t, fs))
+	})
+
+	t.Run("null", func(t *testing.T) {
+		filters, err := sqlbuilder.ParseFilters("id:gte:5;name:notNull")
+		require.NoError(t, err)
+		fs, err := filters.Fields(whitelist)
+		require.NoError(t, err)
+		require.Equal(t, []int{5, 6, 8}, listIds(t, fs))
+
+		filters, err = sqlbuilder.ParseFilters("name:isNull")
+		require.NoError(t, err)
+		fs, err = filters.Fields(whitelist)
+		require.NoError(t, err)
+		require.Equal(t, []int{7}, listIds(t, fs))
+	})
+
+	t.Run("like wildcard", func(t *testing.T) {
+		for value, expected := range map[string][]int{"_": {8}, "%": {8}, "r_8": {8}, "r_": {8}} {
+			filters := sqlbuilder.Filters{{Name: "name", Operator: sqlbuilder.FilterOperator_like, Value: value}}
+			fs, err := filters.Fields(whitelist)
+			require.NoError(t, err)
+			require.Equal(t, expected, listIds(t, fs), value)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		filters, err := sqlbuilder.ParseFiltersJSON([]byte(`{"id":{"gte":2,"lt":5},"name":{"in":["user3","user4","user6"]}}`))
+		require.NoError(t, err)
+		fs, err := filters.Fields(whitelist)
+		require.NoError(t, err)
+		require.Equal(t, []int{3, 4}, listIds(t, fs))
+
+		filters, err = sqlbuilder.ParseFiltersJSON([]byte(`{"name":"user2"}`))
+		require.NoError(t, err)
+		fs, err = filters.Fields(whitelist)
+		require.NoError(t, err)
+		require.Equal(t, []int{2}, listIds(t, fs))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, s := range []string{"password:eq:1", "id:eq:abc", "id:regexp:1", "id", "id:between:1", "id:in:"} {
+			filters, err := sqlbuilder.ParseFilters(s)
+			if err == nil {
+				_, err = filters.Fields(whitelist)
+			}
+			require.ErrorIs(t, err, sqlbuilder.ErrInvalidFilter, s)
+		}
+		_, err := sqlbuilder.ParseFiltersJSON([]byte(`[1]`))
+		require.ErrorIs(t, err, sqlbuilder.ErrInvalidFilter)
+		filters, err := sqlbuilder.ParseFiltersJSON([]byte(`{"id":{"eq":1.7}}`))
+		require.NoError(t, err)
+		_, err = filters.Fields(whitelist)
+		require.ErrorIs(t, err, sqlbuilder.ErrInvalidFilter)
+	})
+
+	t.Run("schema validate", func(t *testing.T) {
+		limited := sqlbuilder.Fields{sqlbuilder.NewStringField("", "name", "名称", 3)}
+		filters, err := sqlbuilder.ParseFilters("name:eq:user1")
+		require.NoError(t, err)
+		fs, err := filters.Fields(limited)
+		require.NoError(t, err)
+		_, err = sqlbuilder.NewListBuilder(table).ToSQL(fs)
+		require.ErrorContains(t, err, "maximum length")
+	})
+}
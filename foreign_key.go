@@ -0,0 +1,159 @@
+package sqlbuilder
+
+import (
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pkg/errors"
+)
+
+// ForeignKeyAction 外键引用记录删除、更新时的动作
+type ForeignKeyAction string
+
+const (
+	ForeignKeyAction_default  ForeignKeyAction = ""          // 不指定,使用数据库默认行为
+	ForeignKeyAction_restrict ForeignKeyAction = "RESTRICT"  // 存在引用时禁止删除、更新
+	ForeignKeyAction_cascade  ForeignKeyAction = "CASCADE"   // 级联删除、更新
+	ForeignKeyAction_setNull  ForeignKeyAction = "SET NULL"  // 引用列置空
+	ForeignKeyAction_noAction ForeignKeyAction = "NO ACTION" // 不处理(mysql 同 RESTRICT,sqlite 在语句结束时检查)
+)
+
+// ForeignKey 外键,GenerateDDL 时生成外键约束;sqlite 需连接开启 foreign_keys(如 dsn 增加 _foreign_keys=on)才会生效
+type ForeignKey struct {
+	ColumnNames    func(table TableConfig) (columnNames []string)       // 当前表外键列,和 Index.ColumnNames 一致返回 DB.Column.Name
+	RefTable       TableConfig                                          // 引用表
+	RefColumnNames func(refTable TableConfig) (refColumnNames []string) // 引用表列,为空时使用引用表主键
+	OnDelete       ForeignKeyAction
+	OnUpdate       ForeignKeyAction
+	// CheckExists 新增、更新前在应用层校验引用记录存在;
+	// 引用表为软删除表(存在 deletedAt 字段)时,数据库外键无法识别已删除记录,开启后已软删除的引用记录视为不存在
+	CheckExists bool
+}
+
+// GetColumnNames 获取外键列,校验列在表中存在
+func (fk ForeignKey) GetColumnNames(table TableConfig) []string {
+	return Index{ColumnNames: fk.ColumnNames}.GetColumnNames(table)
+}
+
+// GetRefColumnNames 获取引用列,未设置时使用引用表主键
+func (fk ForeignKey) GetRefColumnNames() (refColumnNames []string, err error) {
+	if fk.RefColumnNames != nil {
+		return Index{ColumnNames: fk.RefColumnNames}.GetColumnNames(fk.RefTable), nil
+	}
+	primary, ok := fk.RefTable.Indexs.GetPrimary()
+	if !ok {
+		err = errors.Errorf("ForeignKey RefColumnNames is nil and ref table:%s has no primary index", fk.RefTable.Name)
+		return nil, err
+	}
+	return primary.GetColumnNames(fk.RefTable), nil
+}
+
+// ForeignKeyName 外键约束名称 fk_表名_列名
+func (fk ForeignKey) ForeignKeyName(table TableConfig) string {
+	arr := append([]string{"fk", table.DBName.BaseName()}, fk.GetColumnNames(table)...)
+	return strings.Join(arr, "_")
+}
+
+type ForeignKeys []ForeignKey
+
+// GetByName 通过约束名称获取外键
+func (fks ForeignKeys) GetByName(table TableConfig, name string) (fk *ForeignKey, exists bool) {
+	for _, fk := range fks {
+		if strings.EqualFold(fk.ForeignKeyName(table), name) {
+			return &fk, true
+		}
+	}
+	return nil, false
+}
+
+// GetCheckExists 获取需应用层校验的外键
+func (fks ForeignKeys) GetCheckExists() (subFks ForeignKeys) {
+	for _, fk := range fks {
+		if fk.CheckExists {
+			subFks = append(subFks, fk)
+		}
+	}
+	return subFks
+}
+
+func (t TableConfig) AddForeignKeys(foreignKeys ...ForeignKey) TableConfig {
+	fks := make(ForeignKeys, 0, len(t.ForeignKeys)+len(foreignKeys))
+	fks = append(fks, t.ForeignKeys...)
+	t.ForeignKeys = append(fks, foreignKeys...) // 复制,不影响原表配置
+	return t
+}
+
+// CheckForeignKeys 校验写入数据(DB.Column.Name → 值)引用的记录存在,仅校验 CheckExists 的外键;
+// 外键列未全部写入或存在空值时跳过(和数据库外键一致,空值不校验)
+func (t TableConfig) CheckForeignKeys(data any) (err error) {
+	fks := t.ForeignKeys.GetCheckExists()
+	if len(fks) == 0 {
+		return nil
+	}
+	dataMap, ok := data.(map[string]any)
+	if !ok {
+		return nil
+	}
+	handler := t.GetHandler()
+	if isDryRunHandler(handler) { // 试运行不校验
+		return nil
+	}
+	for _, fk := range fks {
+		columnNames := fk.GetColumnNames(t)
+		refColumnNames, err := fk.GetRefColumnNames()
+		if err != nil {
+			return err
+		}
+		if len(columnNames) != len(refColumnNames) {
+			err = errors.Errorf("foreign key:%s columns count %d not equal ref columns count %d", fk.ForeignKeyName(t), len(columnNames), len(refColumnNames))
+			return err
+		}
+		refFs := make(Fields, 0, len(columnNames)+1)
+		for i, columnName := range columnNames {
+			val, ok := dataMap[columnName]
+			if !ok || IsNil(val) {
+				refFs = nil
+				break
+			}
+			refCol, ok := fk.RefTable.Columns.GetByDbName(refColumnNames[i])
+			if !ok {
+				err = errors.Errorf("foreign key:%s ref column:%s not found in table:%s", fk.ForeignKeyName(t), refColumnNames[i], fk.RefTable.Name)
+				return err
+			}
+			refFs = append(refFs, refCol.MakeField(val).AppendWhereFn(ValueFnForward))
+		}
+		if len(refFs) == 0 {
+			continue
+		}
+		if deletedAtCol, ok := fk.RefTable.Columns.GetByFieldName(Field_name_deletedAt); ok {
+			refFs = append(refFs, newNotDeletedField(deletedAtCol))
+		}
+		exists, err := NewExistsBuilder(fk.RefTable).WithHandler(handler).AppendFields(refFs...).Exists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			data, _ := refFs.Data(layer_order...)
+			return &ConstraintError{
+				Kind:       ErrForeignKey,
+				Table:      t.Name,
+				ForeignKey: &fk,
+				Err:        errors.Errorf("referenced record not found in table:%s,value:%v", fk.RefTable.Name, data),
+			}
+		}
+	}
+	return nil
+}
+
+// newNotDeletedField 软删除列为空(NULL 或空字符串)代表记录正常
+func newNotDeletedField(deletedAtCol ColumnConfig) *Field {
+	f := deletedAtCol.GetField().SetValue(nil)
+	f.WhereFns.Reset(ValueFn{
+		Layer: Value_Layer_DBFormat,
+		Fn: func(_ any, f *Field, fs ...*Field) (any, error) {
+			name := f.DBColumnName().FullName()
+			return goqu.Or(goqu.I(name).IsNull(), goqu.I(name).Eq("")), nil
+		},
+	})
+	return f
+}
@@ -0,0 +1,105 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func NewFkParentId(id int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(id, "parentId", "父级ID", 0)
+}
+
+func newForeignKeyTables(checkExists bool) (parent sqlbuilder.TableConfig, child sqlbuilder.TableConfig) {
+	parent = sqlbuilder.NewTableConfig("fk_parent").AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("deleted_at", sqlbuilder.GetField(NewFkDeletedAt)),
+	).AddIndexs(fkPrimaryIndex)
+	child = sqlbuilder.NewTableConfig("fk_child").AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("parent_id", sqlbuilder.GetField(NewFkParentId)),
+	).AddIndexs(fkPrimaryIndex).AddForeignKeys(sqlbuilder.ForeignKey{
+		ColumnNames: func(table sqlbuilder.TableConfig) (columnNames []string) {
+			return []string{"parent_id"}
+		},
+		RefTable:    parent,
+		OnDelete:    sqlbuilder.ForeignKeyAction_cascade,
+		CheckExists: checkExists,
+	})
+	return parent, child
+}
+
+func TestForeignKeyDDL(t *testing.T) {
+	_, child := newForeignKeyTables(false)
+	ddl, err := sqlbuilder.GenerateDDL(sqlbuilder.Driver_mysql, child)
+	require.NoError(t, err)
+	require.Contains(t, ddl, "CONSTRAINT `fk_fk_child_parent_id` FOREIGN KEY (`parent_id`) REFERENCES `fk_parent` (`id`) ON DELETE CASCADE")
+	ddl, err = sqlbuilder.GenerateDDL(sqlbuilder.Driver_sqlite3, child)
+	require.NoError(t, err)
+	require.Contains(t, ddl, "FOREIGN KEY (`parent_id`) REFERENCES `fk_parent` (`id`) ON DELETE CASCADE")
+
+	noPrimary := sqlbuilder.NewTableConfig("fk_no_primary").AddColumns(sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)))
+	child = child.AddForeignKeys(sqlbuilder.ForeignKey{
+		ColumnNames: func(table sqlbuilder.TableConfig) (columnNames []string) {
+			return []string{"parent_id"}
+		},
+		RefTable: noPrimary,
+	})
+	_, err = sqlbuilder.GenerateDDL(sqlbuilder.Driver_mysql, child)
+	require.ErrorContains(t, err, "has no primary index")
+}
+
+func TestForeignKey(t *testing.T) {
+	newTables := func(t *testing.T, foreignKeys bool, checkExists bool) (parent sqlbuilder.TableConfig, child sqlbuilder.TableConfig) {
+		parent, child = newForeignKeyTables(checkExists)
+		db := sqlbuildertest.New(t, parent, child)
+		if foreignKeys { // sqlite 默认不校验外键
+			_, err := db.SqlDB().Exec("PRAGMA foreign_keys = ON")
+			require.NoError(t, err)
+		}
+		db.InsertFixtures(sqlbuildertest.Fixtures{"fk_parent": {{"id": 1, "deleted_at": ""}, {"id": 2, "deleted_at": "2026-01-01 00:00:00"}}})
+		return db.Table("fk_parent"), db.Table("fk_child")
+	}
+
+	t.Run("database", func(t *testing.T) {
+		_, child := newTables(t, true, false)
+		err := sqlbuilder.NewInsertBuilder(child).AppendFields(NewIterateUserId(1), NewFkParentId(1)).Exec()
+		require.NoError(t, err)
+		err = sqlbuilder.NewInsertBuilder(child).AppendFields(NewIterateUserId(2), NewFkParentId(3)).Exec()
+		require.ErrorIs(t, err, sqlbuilder.ErrForeignKey)
+		err = sqlbuilder.NewBatchInsertBuilder(child).AppendFields(sqlbuilder.Fields{NewIterateUserId(3), NewFkParentId(3)}).Exec()
+		require.ErrorIs(t, err, sqlbuilder.ErrForeignKey)
+	})
+
+	t.Run("check exists", func(t *testing.T) {
+		_, child := newTables(t, false, true)
+		err := sqlbuilder.NewInsertBuilder(child).AppendFields(NewIterateUserId(1), NewFkParentId(1)).Exec()
+		require.NoError(t, err)
+
+		err = sqlbuilder.NewInsertBuilder(child).AppendFields(NewIterateUserId(2), NewFkParentId(2)).Exec() // 父级已软删除
+		require.ErrorIs(t, err, sqlbuilder.ErrForeignKey)
+		var constraintErr *sqlbuilder.ConstraintError
+		require.ErrorAs(t, err, &constraintErr)
+		require.NotNil(t, constraintErr.ForeignKey)
+		require.Equal(t, "fk_parent", constraintErr.ForeignKey.RefTable.Name)
+
+		_, err = sqlbuilder.NewUpdateBuilder(child).AppendFields(NewIterateUserId(1).AppendWhereFn(sqlbuilder.ValueFnForward), NewFkParentId(3)).Update()
+		require.ErrorIs(t, err, sqlbuilder.ErrForeignKey)
+
+		_, _, _, err = sqlbuilder.NewSetBuilder(child).AppendFields(NewIterateUserId(3).AppendWhereFn(sqlbuilder.ValueFnForward), NewFkParentId(2)).Set()
+		require.ErrorIs(t, err, sqlbuilder.ErrForeignKey)
+
+		err = sqlbuilder.NewBatchInsertBuilder(child).AppendFields(sqlbuilder.Fields{NewIterateUserId(4), NewFkParentId(1)}, sqlbuilder.Fields{NewIterateUserId(5), NewFkParentId(2)}).Exec()
+		require.ErrorIs(t, err, sqlbuilder.ErrForeignKey)
+	})
+
+	t.Run("check exists query error", func(t *testing.T) {
+		parent, child := newTables(t, false, true)
+		require.NoError(t, parent.GetHandler().Exec("drop table fk_parent"))
+		err := sqlbuilder.NewInsertBuilder(child).AppendFields(NewIterateUserId(1), NewFkParentId(1)).Exec()
+		require.Error(t, err)
+		require.NotErrorIs(t, err, sqlbuilder.ErrForeignKey) // 查询失败不能当作引用记录不存在
+	})
+}
@@ -3,6 +3,7 @@ package sqlbuilder
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -224,6 +225,11 @@ type Handler interface {
 
 }
 
+// RowsQueryer 流式查询接口,原始handler实现,用于大数据量导出等场景逐行读取,避免一次性加载全部记录
+type RowsQueryer interface {
+	QueryRows(ctx context.Context, sql string) (rows Rows, err error)
+}
+
 type GormHandler func() *gorm.DB
 
 func NewGormHandler(getDB func() *gorm.DB) Handler {
@@ -231,12 +237,18 @@ func NewGormHandler(getDB func() *gorm.DB) Handler {
 }
 
 func (h GormHandler) Transaction(fc func(tx Handler) error, opts ...*sql.TxOptions) error {
+	state := newTxState()
 	err := h().Transaction(func(tx *gorm.DB) error {
-		txHandler := NewGormHandler(func() *gorm.DB { return tx })
+		txHandler := newGormTxHandler(tx, state)
 		err := fc(txHandler)
 		return err
 	}, opts...)
-	return err
+	if err != nil {
+		state.rolledBack()
+		return err
+	}
+	state.committed()
+	return nil
 }
 func (h GormHandler) GetDialector() string {
 	return h().Dialector.Name()
@@ -324,6 +336,11 @@ func (h GormHandler) Query(_ context.Context, sql string, result any) (err error
 	return err
 }
 
+// QueryRows 流式查询,调用方负责关闭 rows
+func (h GormHandler) QueryRows(ctx context.Context, sql string) (rows Rows, err error) {
+	return h().WithContext(ctx).Raw(sql).Rows()
+}
+
 func (h GormHandler) Count(sql string) (count int64, err error) {
 	err = h().Raw(sql).Count(&count).Error
 	return count, err
@@ -372,6 +389,19 @@ func GetOriginalHandler(h Handler) Handler {
 	}
 }
 
+// handlerIdentity 句柄对应的数据库标识,区分不同库上执行的相同 sql(如分表总数缓存、分表建表记录);无法识别时返回空字符串,调用方不做缓存
+func handlerIdentity(handler Handler) string {
+	switch h := GetOriginalHandler(handler).(type) {
+	case SqlDBHandler:
+		return fmt.Sprintf("sql.DB@%p", h())
+	case _TxHandler:
+		return fmt.Sprintf("sql.DB@%p", h.db)
+	case GormHandler:
+		return fmt.Sprintf("gorm@%p", h().ConnPool)
+	}
+	return ""
+}
+
 type _HandlerSingleflight struct {
 	handler Handler
 	group   *singleflight.Group
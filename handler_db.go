@@ -9,6 +9,7 @@ import (
 	"unsafe"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 	"github.com/pkg/errors"
 	"github.com/spf13/cast"
 )
@@ -32,20 +33,9 @@ func (h SqlDBHandler) Transaction(fc func(tx Handler) error, opts ...*sql.TxOpti
 	if err != nil {
 		return err
 	}
-
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-
-	txHandler := NewTxHandler(db, tx)
+	txHandler := newTxHandler(db, tx)
 	err = fc(txHandler)
-	if err != nil {
-		return err
-	}
-	_ = tx.Commit()
-	return err
+	return finishTx(tx, txHandler.state, err)
 }
 func (h SqlDBHandler) GetDialector() string {
 	return detectDriver(h())
@@ -132,6 +122,11 @@ func (h SqlDBHandler) Query(_ context.Context, sqlstr string, result any) (err e
 	return nil
 }
 
+// QueryRows 流式查询,调用方负责关闭 rows
+func (h SqlDBHandler) QueryRows(ctx context.Context, sqlstr string) (rows Rows, err error) {
+	return h().QueryContext(ctx, sqlstr)
+}
+
 func (h SqlDBHandler) Count(sql string) (count int64, err error) {
 	err = h().QueryRow(sql).Scan(&count)
 	if err != nil {
@@ -145,23 +140,28 @@ func (h SqlDBHandler) GetDB() *sql.DB {
 }
 
 type _TxHandler struct {
-	db *sql.DB
-	tx *sql.Tx
+	db    *sql.DB
+	tx    *sql.Tx
+	state *txState // 嵌套事务保存点序号、提交/回滚钩子
 }
 
 func NewTxHandler(db *sql.DB, tx *sql.Tx) Handler {
+	return newTxHandler(db, tx)
+}
+
+func newTxHandler(db *sql.DB, tx *sql.Tx) _TxHandler {
 	return _TxHandler{
-		db: db,
-		tx: tx,
+		db:    db,
+		tx:    tx,
+		state: newTxState(),
 	}
 }
 
+// Transaction 嵌套事务使用保存点,内层失败只回滚到保存点;事务已开启,opts 不再生效
 func (h _TxHandler) Transaction(fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
-	err = fc(h)
-	if err != nil {
-		return err
-	}
-	return err
+	return savepointTransaction(h.tx, h.state, func() error {
+		return fc(h)
+	})
 }
 func (h _TxHandler) GetDialector() string {
 	return detectDriver(h.tx)
@@ -267,6 +267,11 @@ func (h _TxHandler) Query(_ context.Context, sqlstr string, result any) (err err
 	return nil
 }
 
+// QueryRows 流式查询,调用方负责关闭 rows
+func (h _TxHandler) QueryRows(ctx context.Context, sqlstr string) (rows Rows, err error) {
+	return h.tx.QueryContext(ctx, sqlstr)
+}
+
 func (h _TxHandler) Count(sql string) (count int64, err error) {
 	err = h.tx.QueryRow(sql).Scan(&count)
 	if err != nil {
@@ -387,6 +392,17 @@ func Scan(rows Rows, dst any) (rowsAffected int64, err error) {
 
 		case reflect.Slice:
 			sliceElemType := elem.Type().Elem()
+			plan, err := nestedSlicePlan(rows, sliceElemType)
+			if err != nil {
+				return 0, err
+			}
+			if plan != nil { // 联表结果含嵌套结构体切片,合并父级记录
+				rowsAffected, err = plan.scanSlice(rows, elem)
+				if err != nil {
+					return rowsAffected, err
+				}
+				break
+			}
 			for rows.Next() {
 				newElem := reflect.New(sliceElemType).Elem()
 				if err = scanIntoStruct(rows, newElem); err != nil {
@@ -407,6 +423,38 @@ func Scan(rows Rows, dst any) (rowsAffected int64, err error) {
 	return rowsAffected, nil
 }
 
+// ScanRow 扫描当前行到dst,调用方负责 rows.Next 及 rows.Close,常用于流式读取
+func ScanRow(rows Rows, dst any) (err error) {
+	if rows == nil {
+		return errors.New("rows is nil")
+	}
+	switch out := dst.(type) {
+	case *map[string]any:
+		if *out == nil {
+			*out = map[string]any{}
+		}
+		return sqlx.MapScan(rows, *out)
+	case map[string]any:
+		return sqlx.MapScan(rows, out)
+	case *[]any:
+		row, err := sqlx.SliceScan(rows)
+		if err != nil {
+			return err
+		}
+		*out = row
+		return nil
+	}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr {
+		return errors.New("dst must be a pointer")
+	}
+	elem := rv.Elem()
+	if elem.Kind() == reflect.Struct && elem.Type() != reflect.TypeFor[time.Time]() {
+		return scanIntoStruct(rows, elem)
+	}
+	return rows.Scan(dst)
+}
+
 // ---- 实现FieldsI接口扫描---- isStructImplements 标记是否为结构体实现FieldsI接口而非指针实现，这种情况需要寻址 找到真正被赋值的副本
 func IsStructImplementFieldsI(dest reflect.Value) (fi FieldsI, ok bool, isStructImplements bool) {
 	dest = reflect.Indirect(dest)
@@ -445,8 +493,8 @@ func scanIntoStruct(rows Rows, dest reflect.Value) error {
 	}
 
 	fi, ok, isStructImplements := IsStructImplementFieldsI(dest)
-	if !ok { // 普通结构体扫描
-		if err := sqlx.StructScan(rows, dest.Addr().Interface()); err != nil {
+	if !ok { // 普通结构体扫描,只扫描当前行(sqlx.StructScan 会读取全部行到切片,不适用于逐行扫描)
+		if err := structScanRow(rows, columns, dest); err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				return nil
 			}
@@ -539,6 +587,20 @@ func scanIntoStruct(rows Rows, dest reflect.Value) error {
 	return nil
 }
 
+var structScanMapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// structScanRow 按db标签将当前行扫描到结构体,带表前缀的列扫描到嵌套结构体(见 nestedScanPlan),缺失的列读取到any后丢弃
+func structScanRow(rows Rows, columns []string, dest reflect.Value) (err error) {
+	if dest.Kind() != reflect.Struct || dest.Type() == reflect.TypeFor[time.Time]() { // 非结构体(如 []int 元素) 直接扫描
+		return rows.Scan(dest.Addr().Interface())
+	}
+	plan, err := getNestedScanPlan(dest.Type(), columns)
+	if err != nil {
+		return err
+	}
+	return plan.scanRow(rows, dest)
+}
+
 // 通用反射函数：从字段指针推导结构体实例指针
 // 参数：
 //
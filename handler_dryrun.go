@@ -0,0 +1,160 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// DryRunStatement 试运行记录的单条语句
+type DryRunStatement struct {
+	Method string `json:"method"` // 调用的Handler方法,如 Exec、InsertWithLastId、Query
+	SQL    string `json:"sql"`
+}
+
+// DryRunHandler 试运行句柄,按执行顺序记录构造器将要执行的sql,不访问数据库,返回预设的结果
+// 查询类方法不修改 result,Exists、Count、RowsAffected、LastInsertId 返回预设值,用于覆盖 Set 等按结果分支执行的场景
+type DryRunHandler struct {
+	driver       Driver
+	exists       bool
+	count        int64
+	rowsAffected int64
+	lastInsertId uint64
+	lock         *sync.Mutex
+	statements   *[]DryRunStatement // 事务内共享记录
+}
+
+// NewDryRunHandler 创建试运行句柄,driver 决定生成sql的方言
+func NewDryRunHandler(driver Driver) *DryRunHandler {
+	return &DryRunHandler{
+		driver:     driver,
+		lock:       &sync.Mutex{},
+		statements: &[]DryRunStatement{},
+	}
+}
+
+// WithExists 设置 Exists、First 返回的结果
+func (h *DryRunHandler) WithExists(exists bool) *DryRunHandler {
+	h.exists = exists
+	return h
+}
+
+// WithCount 设置 Count 返回的结果
+func (h *DryRunHandler) WithCount(count int64) *DryRunHandler {
+	h.count = count
+	return h
+}
+
+// WithRowsAffected 设置写操作返回的影响行数
+func (h *DryRunHandler) WithRowsAffected(rowsAffected int64) *DryRunHandler {
+	h.rowsAffected = rowsAffected
+	return h
+}
+
+// WithLastInsertId 设置新增返回的自增id
+func (h *DryRunHandler) WithLastInsertId(lastInsertId uint64) *DryRunHandler {
+	h.lastInsertId = lastInsertId
+	return h
+}
+
+// Statements 获取已记录的语句,按执行顺序排列
+func (h *DryRunHandler) Statements() (statements []DryRunStatement) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	statements = make([]DryRunStatement, len(*h.statements))
+	copy(statements, *h.statements)
+	return statements
+}
+
+// SQLs 获取已记录的sql,按执行顺序排列
+func (h *DryRunHandler) SQLs() (sqls []string) {
+	for _, statement := range h.Statements() {
+		sqls = append(sqls, statement.SQL)
+	}
+	return sqls
+}
+
+// Reset 清空已记录的语句
+func (h *DryRunHandler) Reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	*h.statements = (*h.statements)[:0]
+}
+
+func (h *DryRunHandler) record(method string, sql string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	*h.statements = append(*h.statements, DryRunStatement{Method: method, SQL: sql})
+}
+
+func (h *DryRunHandler) GetDialector() string {
+	return h.driver.String()
+}
+
+// GetSqlDBHandler 试运行无数据库连接
+func (h *DryRunHandler) GetSqlDBHandler() SqlDBHandler {
+	return func() *sql.DB { return nil }
+}
+
+// Transaction 试运行不开启事务,事务内语句同样记录
+func (h *DryRunHandler) Transaction(fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
+	return fc(h)
+}
+
+func (h *DryRunHandler) Exec(sql string) (err error) {
+	h.record("Exec", sql)
+	return nil
+}
+
+func (h *DryRunHandler) ExecWithRowsAffected(sql string) (rowsAffected int64, err error) {
+	h.record("ExecWithRowsAffected", sql)
+	return h.rowsAffected, nil
+}
+
+func (h *DryRunHandler) InsertWithLastId(sql string) (lastInsertId uint64, rowsAffected int64, err error) {
+	h.record("InsertWithLastId", sql)
+	return h.lastInsertId, h.rowsAffected, nil
+}
+
+func (h *DryRunHandler) First(ctx context.Context, sql string, result any) (exists bool, err error) {
+	h.record("First", sql)
+	return h.exists, nil
+}
+
+func (h *DryRunHandler) Query(ctx context.Context, sql string, result any) (err error) {
+	h.record("Query", sql)
+	return nil
+}
+
+func (h *DryRunHandler) Count(sql string) (count int64, err error) {
+	h.record("Count", sql)
+	return h.count, nil
+}
+
+func (h *DryRunHandler) Exists(sql string) (exists bool, err error) {
+	h.record("Exists", sql)
+	return h.exists, nil
+}
+
+func (h *DryRunHandler) OriginalHandler() Handler {
+	return h
+}
+
+func (h *DryRunHandler) IsOriginalHandler() bool {
+	return true
+}
+
+func isDryRunHandler(handler Handler) bool {
+	_, ok := handler.(*DryRunHandler)
+	return ok
+}
+
+// WithDryRun 使用试运行句柄执行,未设置驱动时沿用表句柄的驱动
+func (p *SQLParam[T]) WithDryRun(dryRun *DryRunHandler) *T {
+	if dryRun.driver == "" && p._Table._handler != nil {
+		dryRun.driver = Driver(p._Table._handler.GetDialector())
+	}
+	return p.WithHandler(dryRun)
+}
+
+var _ Handler = (*DryRunHandler)(nil)
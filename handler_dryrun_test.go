@@ -0,0 +1,49 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+)
+
+func TestDryRunHandler(t *testing.T) {
+	table := newIterateTable(t, 0)
+	t.Run("set exists", func(t *testing.T) {
+		dryRun := sqlbuilder.NewDryRunHandler("").WithExists(true).WithRowsAffected(1)
+		isNotExists, _, rowsAffected, err := sqlbuilder.NewSetBuilder(table).WithDryRun(dryRun).AppendFields(NewIterateUserId(1).AppendWhereFn(sqlbuilder.ValueFnForward), NewIterateUserName("dry")).Set()
+		require.NoError(t, err)
+		require.False(t, isNotExists)
+		require.EqualValues(t, 1, rowsAffected)
+		statements := dryRun.Statements()
+		require.Len(t, statements, 2)
+		require.Equal(t, "Exists", statements[0].Method)
+		require.Equal(t, "ExecWithRowsAffected", statements[1].Method)
+		require.Contains(t, statements[1].SQL, "UPDATE `iterate_user`")
+	})
+	t.Run("set not exists", func(t *testing.T) {
+		dryRun := sqlbuilder.NewDryRunHandler("").WithLastInsertId(9)
+		_, lastInsertId, _, err := sqlbuilder.NewSetBuilder(table).WithDryRun(dryRun).AppendFields(NewIterateUserId(9).AppendWhereFn(sqlbuilder.ValueFnForward), NewIterateUserName("dry")).Set()
+		require.NoError(t, err)
+		require.EqualValues(t, 9, lastInsertId)
+		sqls := dryRun.SQLs()
+		require.Len(t, sqls, 2)
+		require.Contains(t, sqls[1], "INSERT INTO `iterate_user`")
+	})
+	exists, err := sqlbuilder.NewExistsBuilder(table).AppendFields(NewIterateUserId(9).AppendWhereFn(sqlbuilder.ValueFnForward)).Exists()
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestExplain(t *testing.T) {
+	table := newIterateTable(t, 3)
+	plans, err := sqlbuilder.NewListBuilder(table).AppendFields(NewIterateUserName("user1").AppendWhereFn(sqlbuilder.ValueFnForward)).Explain()
+	require.NoError(t, err)
+	require.True(t, plans.HasFullTableScan())
+	require.Equal(t, "iterate_user", plans.FullTableScans()[0].Table)
+
+	plans, err = sqlbuilder.NewFirstBuilder(table).AppendFields(NewIterateUserId(1).AppendWhereFn(sqlbuilder.ValueFnForward)).Explain()
+	require.NoError(t, err)
+	require.NotEmpty(t, plans)
+	require.False(t, plans.HasFullTableScan())
+}
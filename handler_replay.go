@@ -0,0 +1,422 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrReplayUnexpectedStatement 严格回放模式下执行了录制文件中不存在(或已用完)的语句
+var ErrReplayUnexpectedStatement = errors.New("replay unexpected statement")
+
+// ReplayRecord 录制的单条语句及结果
+type ReplayRecord struct {
+	Method       string          `json:"method"`
+	SQL          string          `json:"sql"` // 规范化后的sql
+	Result       json.RawMessage `json:"result,omitempty"`
+	Exists       bool            `json:"exists,omitempty"`
+	Count        int64           `json:"count,omitempty"`
+	RowsAffected int64           `json:"rowsAffected,omitempty"`
+	LastInsertId uint64          `json:"lastInsertId,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	ErrorKind    string          `json:"errorKind,omitempty"` // 错误类型,回放时还原为对应哨兵错误,见 replayErrorKinds
+}
+
+// ReplayGolden 录制文件内容
+type ReplayGolden struct {
+	Dialector string         `json:"dialector"`
+	Records   []ReplayRecord `json:"records"`
+}
+
+type replayState struct {
+	lock    sync.Mutex
+	golden  ReplayGolden
+	used    []bool
+	strict  bool
+	replay  bool
+	matcher ReplaySQLMatcher
+	handler Handler // 录制模式下的真实句柄
+}
+
+// ReplaySQLMatcher 回放时判断录制的sql与执行的sql是否匹配,两者均已规范化
+type ReplaySQLMatcher func(recorded string, sql string) bool
+
+// ReplayMatchExact 规范化后完全一致,默认匹配方式
+func ReplayMatchExact(recorded string, sql string) bool {
+	return recorded == sql
+}
+
+// ReplayMatchIgnoreLiterals 忽略字符串、数字字面量差异,用于语句包含当前时间、生成的id等每次执行都不同的值
+func ReplayMatchIgnoreLiterals(recorded string, sql string) bool {
+	return NormalizeSQLLiterals(recorded) == NormalizeSQLLiterals(sql)
+}
+
+// ReplayHandler 录制/回放句柄:
+// 录制模式包裹真实句柄执行并记录 sql→结果,通过 SaveGoldenFile 保存;
+// 回放模式不访问数据库,按规范化sql匹配录制结果,相同sql按录制顺序依次返回
+type ReplayHandler struct {
+	state   *replayState
+	handler Handler // 录制模式下当前句柄,事务中为事务句柄
+}
+
+// NewRecordHandler 录制模式,包裹真实句柄
+func NewRecordHandler(handler Handler) *ReplayHandler {
+	return &ReplayHandler{
+		state: &replayState{
+			golden:  ReplayGolden{Dialector: handler.GetDialector()},
+			handler: handler,
+		},
+		handler: handler,
+	}
+}
+
+// NewReplayHandler 回放模式,strict 为 true 时遇到未录制的语句返回 ErrReplayUnexpectedStatement,否则返回零值结果
+func NewReplayHandler(golden ReplayGolden, strict bool) *ReplayHandler {
+	return &ReplayHandler{
+		state: &replayState{
+			golden:  golden,
+			used:    make([]bool, len(golden.Records)),
+			strict:  strict,
+			replay:  true,
+			matcher: ReplayMatchExact,
+		},
+	}
+}
+
+// WithSQLMatcher 设置回放sql匹配方式,默认 ReplayMatchExact
+func (h *ReplayHandler) WithSQLMatcher(matcher ReplaySQLMatcher) *ReplayHandler {
+	h.state.lock.Lock()
+	defer h.state.lock.Unlock()
+	h.state.matcher = matcher
+	return h
+}
+
+// LoadReplayHandler 从录制文件创建回放句柄
+func LoadReplayHandler(filename string, strict bool) (h *ReplayHandler, err error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	golden := ReplayGolden{}
+	err = json.Unmarshal(b, &golden)
+	if err != nil {
+		err = errors.WithMessagef(err, "replay golden file:%s", filename)
+		return nil, err
+	}
+	return NewReplayHandler(golden, strict), nil
+}
+
+// SaveGoldenFile 保存录制结果
+func (h *ReplayHandler) SaveGoldenFile(filename string) (err error) {
+	h.state.lock.Lock()
+	b, err := json.MarshalIndent(h.state.golden, "", "  ")
+	h.state.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0o644)
+}
+
+// Golden 获取录制内容
+func (h *ReplayHandler) Golden() (golden ReplayGolden) {
+	h.state.lock.Lock()
+	defer h.state.lock.Unlock()
+	golden = h.state.golden
+	golden.Records = append([]ReplayRecord(nil), h.state.golden.Records...)
+	return golden
+}
+
+// Unused 回放模式下未被使用的录制语句,可用于断言用例执行了全部预期语句
+func (h *ReplayHandler) Unused() (records []ReplayRecord) {
+	h.state.lock.Lock()
+	defer h.state.lock.Unlock()
+	for i, used := range h.state.used {
+		if !used {
+			records = append(records, h.state.golden.Records[i])
+		}
+	}
+	return records
+}
+
+// NormalizeSQL 规范化sql:合并空白字符,去除末尾分号
+func NormalizeSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	return strings.TrimRight(sql, "; ")
+}
+
+// NormalizeSQLLiterals 在 NormalizeSQL 基础上将字符串、数字字面量替换为 ?,引号包裹的标识符保持不变
+func NormalizeSQLLiterals(sql string) string {
+	sql = NormalizeSQL(sql)
+	var w strings.Builder
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'':
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\\' { // mysql 反斜杠转义
+					i++
+					continue
+				}
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' { // 两个单引号转义
+						i++
+						continue
+					}
+					break
+				}
+			}
+			w.WriteByte('?')
+		case c == '`' || c == '"':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				end = len(sql) - i - 1
+			}
+			w.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case isASCIIDigit(c) && (i == 0 || !isIdentifierByte(sql[i-1])):
+			for i+1 < len(sql) && (isASCIIDigit(sql[i+1]) || sql[i+1] == '.') {
+				i++
+			}
+			w.WriteByte('?')
+		default:
+			w.WriteByte(c)
+		}
+	}
+	return w.String()
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '.' || isASCIIDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// replayErrorKinds 录制时按哨兵错误记录错误类型,回放时还原,保证 errors.Is、TranslateError 结果与录制时一致
+var replayErrorKinds = []struct {
+	name       string
+	err        error
+	constraint bool // 约束错误还原为 *ConstraintError
+}{
+	{name: "duplicateKey", err: ErrDuplicateKey, constraint: true},
+	{name: "foreignKey", err: ErrForeignKey, constraint: true},
+	{name: "notNull", err: ErrNotNull, constraint: true},
+	{name: "dataTooLong", err: ErrDataTooLong, constraint: true},
+	{name: "notFound", err: ErrNotFound},
+	{name: "noRows", err: sql.ErrNoRows},
+	{name: "canceled", err: context.Canceled},
+	{name: "deadlineExceeded", err: context.DeadlineExceeded},
+}
+
+func replayErrorKind(err error) string {
+	err = TableConfig{}.TranslateError(err)
+	for _, kind := range replayErrorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.name
+		}
+	}
+	return ""
+}
+
+// replayError 回放还原的非约束错误,错误信息为录制内容
+type replayError struct {
+	msg  string
+	kind error
+}
+
+func (e *replayError) Error() string {
+	return e.msg
+}
+
+func (e *replayError) Unwrap() error {
+	return e.kind
+}
+
+func (r ReplayRecord) error() error {
+	if r.Error == "" {
+		return nil
+	}
+	for _, kind := range replayErrorKinds {
+		if kind.name != r.ErrorKind {
+			continue
+		}
+		if kind.constraint {
+			return &ConstraintError{Kind: kind.err, Err: errors.New(r.Error)}
+		}
+		return &replayError{msg: r.Error, kind: kind.err}
+	}
+	return errors.New(r.Error)
+}
+
+func (h *ReplayHandler) isReplay() bool {
+	return h.state.replay
+}
+
+func (h *ReplayHandler) save(record ReplayRecord, err error) {
+	if err != nil {
+		record.Error = err.Error()
+		record.ErrorKind = replayErrorKind(err)
+	}
+	h.state.lock.Lock()
+	defer h.state.lock.Unlock()
+	h.state.golden.Records = append(h.state.golden.Records, record)
+}
+
+// match 回放时按方法和规范化sql匹配首个未使用的录制
+func (h *ReplayHandler) match(method string, sql string) (record ReplayRecord, err error) {
+	sql = NormalizeSQL(sql)
+	h.state.lock.Lock()
+	defer h.state.lock.Unlock()
+	for i, r := range h.state.golden.Records {
+		if h.state.used[i] || r.Method != method || !h.state.matcher(r.SQL, sql) {
+			continue
+		}
+		h.state.used[i] = true
+		return r, r.error()
+	}
+	if h.state.strict {
+		err = errors.WithMessagef(ErrReplayUnexpectedStatement, "%s:%s", method, sql)
+		return record, err
+	}
+	return record, nil
+}
+
+func (h *ReplayHandler) GetDialector() string {
+	return h.state.golden.Dialector
+}
+
+// GetSqlDBHandler 回放模式无数据库连接
+func (h *ReplayHandler) GetSqlDBHandler() SqlDBHandler {
+	if h.isReplay() {
+		return func() *sql.DB { return nil }
+	}
+	return h.handler.GetSqlDBHandler()
+}
+
+// Transaction 录制模式在真实句柄上开启事务,事务内语句记录到同一文件;回放模式直接执行
+func (h *ReplayHandler) Transaction(fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
+	if h.isReplay() {
+		return fc(h)
+	}
+	return h.handler.Transaction(func(tx Handler) error {
+		return fc(&ReplayHandler{state: h.state, handler: tx})
+	}, opts...)
+}
+
+func (h *ReplayHandler) Exec(sql string) (err error) {
+	if h.isReplay() {
+		_, err = h.match("Exec", sql)
+		return err
+	}
+	err = h.handler.Exec(sql)
+	h.save(ReplayRecord{Method: "Exec", SQL: NormalizeSQL(sql)}, err)
+	return err
+}
+
+func (h *ReplayHandler) ExecWithRowsAffected(sql string) (rowsAffected int64, err error) {
+	if h.isReplay() {
+		record, err := h.match("ExecWithRowsAffected", sql)
+		return record.RowsAffected, err
+	}
+	rowsAffected, err = h.handler.ExecWithRowsAffected(sql)
+	h.save(ReplayRecord{Method: "ExecWithRowsAffected", SQL: NormalizeSQL(sql), RowsAffected: rowsAffected}, err)
+	return rowsAffected, err
+}
+
+func (h *ReplayHandler) InsertWithLastId(sql string) (lastInsertId uint64, rowsAffected int64, err error) {
+	if h.isReplay() {
+		record, err := h.match("InsertWithLastId", sql)
+		return record.LastInsertId, record.RowsAffected, err
+	}
+	lastInsertId, rowsAffected, err = h.handler.InsertWithLastId(sql)
+	h.save(ReplayRecord{Method: "InsertWithLastId", SQL: NormalizeSQL(sql), LastInsertId: lastInsertId, RowsAffected: rowsAffected}, err)
+	return lastInsertId, rowsAffected, err
+}
+
+func (h *ReplayHandler) First(ctx context.Context, sql string, result any) (exists bool, err error) {
+	if h.isReplay() {
+		record, err := h.match("First", sql)
+		if err != nil {
+			return false, err
+		}
+		if record.Exists && len(record.Result) > 0 {
+			err = json.Unmarshal(record.Result, result)
+			if err != nil {
+				return false, err
+			}
+		}
+		return record.Exists, nil
+	}
+	exists, err = h.handler.First(ctx, sql, result)
+	record := ReplayRecord{Method: "First", SQL: NormalizeSQL(sql), Exists: exists}
+	if err == nil && exists {
+		record.Result, err = json.Marshal(result)
+	}
+	h.save(record, err)
+	return exists, err
+}
+
+func (h *ReplayHandler) Query(ctx context.Context, sql string, result any) (err error) {
+	if h.isReplay() {
+		record, err := h.match("Query", sql)
+		if err != nil {
+			return err
+		}
+		if len(record.Result) > 0 {
+			err = json.Unmarshal(record.Result, result)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = h.handler.Query(ctx, sql, result)
+	record := ReplayRecord{Method: "Query", SQL: NormalizeSQL(sql)}
+	if err == nil {
+		record.Result, err = json.Marshal(result)
+	}
+	h.save(record, err)
+	return err
+}
+
+func (h *ReplayHandler) Count(sql string) (count int64, err error) {
+	if h.isReplay() {
+		record, err := h.match("Count", sql)
+		return record.Count, err
+	}
+	count, err = h.handler.Count(sql)
+	h.save(ReplayRecord{Method: "Count", SQL: NormalizeSQL(sql), Count: count}, err)
+	return count, err
+}
+
+func (h *ReplayHandler) Exists(sql string) (exists bool, err error) {
+	if h.isReplay() {
+		record, err := h.match("Exists", sql)
+		return record.Exists, err
+	}
+	exists, err = h.handler.Exists(sql)
+	h.save(ReplayRecord{Method: "Exists", SQL: NormalizeSQL(sql), Exists: exists}, err)
+	return exists, err
+}
+
+func (h *ReplayHandler) OriginalHandler() Handler {
+	return h
+}
+
+func (h *ReplayHandler) IsOriginalHandler() bool {
+	return true
+}
+
+// isReplayHandler 回放模式句柄,不访问数据库
+func isReplayHandler(handler Handler) bool {
+	h, ok := handler.(*ReplayHandler)
+	return ok && h.isReplay()
+}
+
+var _ Handler = (*ReplayHandler)(nil)
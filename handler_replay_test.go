@@ -0,0 +1,88 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+)
+
+func TestReplayHandler(t *testing.T) {
+	ctx := context.Background()
+	table := newIterateTable(t, 3)
+	run := func(repository sqlbuilder.Repository) (user iterateUser, users []iterateUser, total int64, err error) {
+		err = repository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(4), NewIterateUserName("user4")})
+		if err != nil {
+			return user, nil, 0, err
+		}
+		_, err = repository.First(ctx, &user, sqlbuilder.Fields{NewIterateUserId(4).AppendWhereFn(sqlbuilder.ValueFnForward)})
+		if err != nil {
+			return user, nil, 0, err
+		}
+		total, err = repository.Pagination(ctx, &users, sqlbuilder.Fields{NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(2).SetTag(sqlbuilder.Field_tag_pageSize)})
+		return user, users, total, err
+	}
+
+	recorder := sqlbuilder.NewRecordHandler(table.GetHandler())
+	user, users, total, err := run(table.WithHandler(recorder).Repository())
+	require.NoError(t, err)
+	require.Equal(t, iterateUser{Id: 4, Name: "user4"}, user)
+	filename := filepath.Join(t.TempDir(), "replay.json")
+	require.NoError(t, recorder.SaveGoldenFile(filename))
+
+	t.Run("replay", func(t *testing.T) {
+		replayer, err := sqlbuilder.LoadReplayHandler(filename, true)
+		require.NoError(t, err)
+		replayUser, replayUsers, replayTotal, err := run(table.WithHandler(replayer).Repository())
+		require.NoError(t, err)
+		require.Equal(t, user, replayUser)
+		require.Equal(t, users, replayUsers)
+		require.Equal(t, total, replayTotal)
+	})
+
+	t.Run("strict unexpected statement", func(t *testing.T) {
+		replayer, err := sqlbuilder.LoadReplayHandler(filename, true)
+		require.NoError(t, err)
+		_, err = table.WithHandler(replayer).Repository().Count(ctx, sqlbuilder.Fields{NewIterateUserName("none").AppendWhereFn(sqlbuilder.ValueFnForward)})
+		require.ErrorIs(t, err, sqlbuilder.ErrReplayUnexpectedStatement)
+	})
+	t.Run("ignore literals", func(t *testing.T) {
+		table := newIterateTable(t, 0)
+		insert := func(handler sqlbuilder.Handler) error {
+			return table.WithHandler(handler).Repository().Insert(ctx, sqlbuilder.Fields{NewIterateUserId(1), NewIterateUserName(time.Now().Format(time.RFC3339Nano))}) // 每次执行值不同
+		}
+		recorder := sqlbuilder.NewRecordHandler(table.GetHandler())
+		require.NoError(t, insert(recorder))
+
+		err := insert(sqlbuilder.NewReplayHandler(recorder.Golden(), true))
+		require.ErrorIs(t, err, sqlbuilder.ErrReplayUnexpectedStatement)
+		err = insert(sqlbuilder.NewReplayHandler(recorder.Golden(), true).WithSQLMatcher(sqlbuilder.ReplayMatchIgnoreLiterals))
+		require.NoError(t, err)
+	})
+
+	t.Run("error kind", func(t *testing.T) {
+		table := newIterateTable(t, 1)
+		insertSQL := "insert into `iterate_user` (`id`,`name`) values (1,'dup')"
+		recorder := sqlbuilder.NewRecordHandler(table.GetHandler())
+		err := recorder.Exec(insertSQL)
+		require.ErrorIs(t, table.TranslateError(err), sqlbuilder.ErrDuplicateKey)
+		filename := filepath.Join(t.TempDir(), "replay_error.json")
+		require.NoError(t, recorder.SaveGoldenFile(filename))
+
+		replayer, err := sqlbuilder.LoadReplayHandler(filename, true)
+		require.NoError(t, err)
+		err = table.TranslateError(replayer.Exec(insertSQL))
+		require.ErrorIs(t, err, sqlbuilder.ErrDuplicateKey)
+		var constraintErr *sqlbuilder.ConstraintError
+		require.ErrorAs(t, err, &constraintErr)
+		require.Equal(t, "iterate_user", constraintErr.Table)
+	})
+}
+
+func TestNormalizeSQLLiterals(t *testing.T) {
+	sql := "SELECT * FROM `t_1` WHERE (`name` = 'it''s 2') AND `id` IN (1, 2) AND `price` > 1.5 AND \"v2\" = 'a\\'b' LIMIT 10;"
+	require.Equal(t, "SELECT * FROM `t_1` WHERE (`name` = ?) AND `id` IN (?, ?) AND `price` > ? AND \"v2\" = ? LIMIT ?", sqlbuilder.NormalizeSQLLiterals(sql))
+}
@@ -0,0 +1,219 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// RetryClassifier 判断错误是否可以重试整个事务(如死锁、锁等待超时、数据库繁忙)
+type RetryClassifier func(err error) (retryable bool)
+
+var retryClassifiers = struct {
+	sync.RWMutex
+	fns map[string]RetryClassifier
+}{fns: map[string]RetryClassifier{}}
+
+// RegisterRetryClassifier 注册驱动事务重试错误分类器,同名驱动后注册的生效,可在事务执行期间调用
+func RegisterRetryClassifier(driver Driver, classifier RetryClassifier) {
+	retryClassifiers.Lock()
+	defer retryClassifiers.Unlock()
+	retryClassifiers.fns[strings.ToLower(driver.String())] = classifier
+}
+
+// RetryClassifier 获取驱动事务重试错误分类器
+func (d Driver) RetryClassifier() (classifier RetryClassifier, ok bool) {
+	retryClassifiers.RLock()
+	defer retryClassifiers.RUnlock()
+	classifier, ok = retryClassifiers.fns[strings.ToLower(d.String())]
+	return classifier, ok
+}
+
+const (
+	mysql_ER_LOCK_WAIT_TIMEOUT = 1205
+	mysql_ER_LOCK_DEADLOCK     = 1213
+)
+
+// MysqlRetryClassifier mysql 死锁(1213)、锁等待超时(1205)
+func MysqlRetryClassifier(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysql_ER_LOCK_DEADLOCK || mysqlErr.Number == mysql_ER_LOCK_WAIT_TIMEOUT
+	}
+	return false
+}
+
+// SQLite3RetryClassifier sqlite SQLITE_BUSY、SQLITE_LOCKED
+func SQLite3RetryClassifier(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+func init() {
+	RegisterRetryClassifier(Driver_mysql, MysqlRetryClassifier)
+	RegisterRetryClassifier(Driver_sqlite3, SQLite3RetryClassifier)
+	RegisterRetryClassifier(_Driver_sqlite, SQLite3RetryClassifier)
+}
+
+// TxRetryMetrics 事务重试统计
+type TxRetryMetrics struct {
+	Transactions atomic.Int64 // 执行的事务数(不含重试)
+	Retries      atomic.Int64 // 重试次数
+	Exhausted    atomic.Int64 // 重试次数用尽仍失败的事务数
+}
+
+// TxRetryPolicy 事务重试策略,可重试错误时重新执行整个事务函数
+type TxRetryPolicy struct {
+	MaxAttempts int                          // 最大执行次数(含首次),小于等于1时不重试
+	BaseDelay   time.Duration                // 首次重试等待时长,按2的指数递增
+	MaxDelay    time.Duration                // 最大等待时长,0 表示不限制
+	Classifier  RetryClassifier              // 错误分类器,为空时使用驱动注册的分类器
+	OnRetry     func(attempt int, err error) // 重试前回调,attempt 为即将执行的次数
+	Metrics     *TxRetryMetrics              // 统计,为空时不统计
+}
+
+// DefaultTxRetryPolicy 默认事务重试策略
+func DefaultTxRetryPolicy() TxRetryPolicy {
+	return TxRetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   20 * time.Millisecond,
+		MaxDelay:    time.Second,
+		Metrics:     &TxRetryMetrics{},
+	}
+}
+
+// backoff 指数退避,在 [delay/2,delay] 间随机抖动
+func (p TxRetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) { // 溢出或超过上限
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(delay-half)+1))
+}
+
+func (p TxRetryPolicy) classifier(driver Driver) RetryClassifier {
+	if p.Classifier != nil {
+		return p.Classifier
+	}
+	classifier, ok := driver.RetryClassifier()
+	if !ok {
+		return func(err error) bool { return false }
+	}
+	return classifier
+}
+
+// Transaction 按重试策略执行事务;句柄已在事务中时不重试(死锁时整个外层事务已失效,由外层重试)
+func (p TxRetryPolicy) Transaction(handler Handler, fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
+	return p.TransactionContext(context.Background(), handler, fc, opts...)
+}
+
+// TransactionContext 同 Transaction,退避等待期间 ctx 取消时不再重试,返回 ctx 错误
+func (p TxRetryPolicy) TransactionContext(ctx context.Context, handler Handler, fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
+	original := GetOriginalHandler(handler) // 中间件包装的事务句柄同样不重试
+	if _, ok := original.(TxHooker); ok && inTransaction(original) {
+		return handler.Transaction(fc, opts...)
+	}
+	if p.Metrics != nil {
+		p.Metrics.Transactions.Add(1)
+	}
+	classifier := p.classifier(Driver(handler.GetDialector()))
+	maxAttempts := max(p.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err = handler.Transaction(fc, opts...)
+		if err == nil || !classifier(err) {
+			return err
+		}
+		if attempt >= maxAttempts {
+			if p.Metrics != nil {
+				p.Metrics.Exhausted.Add(1)
+			}
+			return errors.WithMessagef(err, "transaction failed after %d attempts", attempt)
+		}
+		if p.Metrics != nil {
+			p.Metrics.Retries.Add(1)
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt+1, err)
+		}
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.WithMessagef(ctx.Err(), "transaction retry canceled after %d attempts,last error:%s", attempt, err.Error())
+		case <-timer.C:
+		}
+	}
+}
+
+type _HandlerTxRetry struct {
+	handler Handler
+	policy  TxRetryPolicy
+}
+
+// HandlerMiddlewareTxRetry 事务重试中间件,Transaction 遇到可重试错误时重新执行整个事务函数
+func HandlerMiddlewareTxRetry(policy TxRetryPolicy) HandlerMiddleware {
+	return func(handler Handler) Handler {
+		return _HandlerTxRetry{
+			handler: handler,
+			policy:  policy,
+		}
+	}
+}
+
+func (hc _HandlerTxRetry) OriginalHandler() Handler {
+	return GetOriginalHandler(hc.handler)
+}
+func (hc _HandlerTxRetry) IsOriginalHandler() bool {
+	return false
+}
+func (hc _HandlerTxRetry) GetDialector() string {
+	return hc.handler.GetDialector()
+}
+
+func (hc _HandlerTxRetry) GetSqlDBHandler() SqlDBHandler {
+	return hc.handler.GetSqlDBHandler()
+}
+
+func (hc _HandlerTxRetry) Transaction(fc func(tx Handler) error, opts ...*sql.TxOptions) error {
+	return hc.policy.Transaction(hc.handler, fc, opts...)
+}
+
+func (hc _HandlerTxRetry) Exec(sql string) (err error) {
+	return hc.handler.Exec(sql)
+}
+func (hc _HandlerTxRetry) ExecWithRowsAffected(sql string) (rowsAffected int64, err error) {
+	return hc.handler.ExecWithRowsAffected(sql)
+}
+func (hc _HandlerTxRetry) InsertWithLastId(sql string) (lastInsertId uint64, rowsAffected int64, err error) {
+	return hc.handler.InsertWithLastId(sql)
+}
+func (hc _HandlerTxRetry) First(ctx context.Context, sql string, result any) (exists bool, err error) {
+	return hc.handler.First(ctx, sql, result)
+}
+func (hc _HandlerTxRetry) Query(ctx context.Context, sql string, result any) (err error) {
+	return hc.handler.Query(ctx, sql, result)
+}
+func (hc _HandlerTxRetry) Count(sql string) (count int64, err error) {
+	return hc.handler.Count(sql)
+}
+func (hc _HandlerTxRetry) Exists(sql string) (exists bool, err error) {
+	return hc.handler.Exists(sql)
+}
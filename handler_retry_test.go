@@ -0,0 +1,95 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+)
+
+func TestTxRetry(t *testing.T) {
+	require.True(t, sqlbuilder.MysqlRetryClassifier(errors.WithMessage(&mysql.MySQLError{Number: 1213}, "deadlock")))
+	require.True(t, sqlbuilder.MysqlRetryClassifier(&mysql.MySQLError{Number: 1205}))
+	require.False(t, sqlbuilder.MysqlRetryClassifier(&mysql.MySQLError{Number: 1062}))
+	require.True(t, sqlbuilder.SQLite3RetryClassifier(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	require.False(t, sqlbuilder.SQLite3RetryClassifier(errors.New("busy")))
+
+	ctx := context.Background()
+	table := newIterateTable(t, 0)
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+	t.Run("repository", func(t *testing.T) {
+		policy := sqlbuilder.DefaultTxRetryPolicy()
+		policy.BaseDelay = 0
+		attempts := 0
+		err := table.Repository().WithTxRetryPolicy(policy).Transaction(func(txRepository sqlbuilder.Repository) (err error) {
+			attempts++
+			err = txRepository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(attempts), NewIterateUserName("user")})
+			if err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return busy
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+		require.EqualValues(t, 2, policy.Metrics.Retries.Load())
+		users := make([]iterateUser, 0)
+		require.NoError(t, sqlbuilder.NewListBuilder(table).List(&users))
+		require.Equal(t, []iterateUser{{Id: 3, Name: "user"}}, users) // 失败的尝试已回滚
+	})
+	t.Run("middleware exhausted", func(t *testing.T) {
+		policy := sqlbuilder.TxRetryPolicy{MaxAttempts: 2, Metrics: &sqlbuilder.TxRetryMetrics{}}
+		handler := sqlbuilder.ChainHandler(table.GetHandler(), sqlbuilder.HandlerMiddlewareTxRetry(policy))
+		attempts := 0
+		err := handler.Transaction(func(tx sqlbuilder.Handler) error {
+			attempts++
+			return busy
+		})
+		require.ErrorIs(t, err, busy)
+		require.Equal(t, 2, attempts)
+		require.EqualValues(t, 1, policy.Metrics.Exhausted.Load())
+	})
+	t.Run("not retryable", func(t *testing.T) {
+		attempts := 0
+		errOther := errors.New("other")
+		err := table.Repository().WithTxRetryPolicy(sqlbuilder.TxRetryPolicy{MaxAttempts: 3}).Transaction(func(txRepository sqlbuilder.Repository) (err error) {
+			attempts++
+			return errOther
+		})
+		require.ErrorIs(t, err, errOther)
+		require.Equal(t, 1, attempts)
+	})
+	t.Run("nested wrapped tx", func(t *testing.T) {
+		policy := sqlbuilder.TxRetryPolicy{MaxAttempts: 3, Metrics: &sqlbuilder.TxRetryMetrics{}}
+		attempts := 0
+		err := table.GetHandler().Transaction(func(tx sqlbuilder.Handler) error {
+			wrapped := sqlbuilder.ChainHandler(tx, sqlbuilder.HandlerMiddlewareSingleflight, sqlbuilder.HandlerMiddlewareTxRetry(policy))
+			return wrapped.Transaction(func(tx sqlbuilder.Handler) error {
+				attempts++
+				return busy
+			})
+		})
+		require.ErrorIs(t, err, busy)
+		require.Equal(t, 1, attempts) // 已在事务中,由外层重试
+		require.EqualValues(t, 0, policy.Metrics.Retries.Load())
+	})
+	t.Run("backoff canceled", func(t *testing.T) {
+		policy := sqlbuilder.TxRetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		attempts := 0
+		err := policy.TransactionContext(ctx, table.GetHandler(), func(tx sqlbuilder.Handler) error {
+			attempts++
+			return busy
+		})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,427 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+)
+
+// ErrShardedHandlerRoute 分库路由失败,如路由到不存在的分库、原始sql无法路由等
+var ErrShardedHandlerRoute = errors.New("sharded handler route failed")
+
+// ErrShardedTransaction 事务跨分库
+var ErrShardedTransaction = errors.New("transaction spans multiple database shards")
+
+// ShardedRouteFn 分库路由函数,根据字段返回分库标识(如按用户id取模),未包含分库键时返回空,表示所有分库
+type ShardedRouteFn func(fs Fields) (shardKeys []string)
+
+// ShardedHandler 分库句柄,持有分库标识到Handler的映射,构造器执行前根据字段路由到具体Handler:
+// 新增、Set 必须路由到唯一分库;更新、删除、查询未指定分库键时在所有分库执行(fan-out)
+// 需直接设置为TableConfig的句柄,经过其它中间件包裹后无法识别
+type ShardedHandler struct {
+	handlers map[string]Handler
+	keys     []string // 有序分库标识,保证fan-out 顺序稳定
+	routeFn  ShardedRouteFn
+	tx       *shardedTx // 事务中,首次路由时在对应分库开启事务,后续路由到其它分库报错
+}
+
+// NewShardedHandler 分库句柄至少包含一个分库,各分库句柄不能为空且驱动一致
+func NewShardedHandler(handlers map[string]Handler, routeFn ShardedRouteFn) (h *ShardedHandler, err error) {
+	if len(handlers) == 0 {
+		err = errors.WithMessage(ErrShardedHandlerRoute, "no shard handler")
+		return nil, err
+	}
+	keys := make([]string, 0, len(handlers))
+	for key, handler := range handlers {
+		if handler == nil {
+			err = errors.WithMessagef(ErrShardedHandlerRoute, "shard:%s handler is nil", key)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	dialector := handlers[keys[0]].GetDialector()
+	for _, key := range keys[1:] {
+		if d := handlers[key].GetDialector(); d != dialector {
+			err = errors.Errorf("sharded handler dialector must be same,shard:%s is %s,shard:%s is %s", keys[0], dialector, key, d)
+			return nil, err
+		}
+	}
+	h = &ShardedHandler{
+		handlers: handlers,
+		keys:     keys,
+		routeFn:  routeFn,
+	}
+	return h, nil
+}
+
+// Shard 获取指定分库句柄
+func (h *ShardedHandler) Shard(shardKey string) (handler Handler, err error) {
+	handler, ok := h.handlers[shardKey]
+	if !ok {
+		err = errors.WithMessagef(ErrShardedHandlerRoute, "shard:%s not found", shardKey)
+		return nil, err
+	}
+	return handler, nil
+}
+
+// Route 根据字段路由分库句柄,事务中只能路由到唯一分库
+func (h *ShardedHandler) Route(fs Fields) (handlers []Handler, err error) {
+	shardKeys := h.shardKeys(fs)
+	if h.tx != nil {
+		if len(shardKeys) != 1 {
+			err = errors.WithMessagef(ErrShardedTransaction, "route to shards:%s", strings.Join(shardKeys, ","))
+			return nil, err
+		}
+		handler, err := h.tx.handler(h, shardKeys[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Handler{handler}, nil
+	}
+	for _, shardKey := range shardKeys {
+		handler, err := h.Shard(shardKey)
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, handler)
+	}
+	if len(handlers) == 0 {
+		err = errors.WithMessage(ErrShardedHandlerRoute, "no shard handler")
+		return nil, err
+	}
+	return handlers, nil
+}
+
+// shardKeys 根据字段获取分库标识,未包含分库键时返回所有分库
+func (h *ShardedHandler) shardKeys(fs Fields) (shardKeys []string) {
+	shardKeys = h.keys
+	if h.routeFn != nil {
+		if routed := h.routeFn(fs); len(routed) > 0 {
+			shardKeys = routed
+		}
+	}
+	return shardKeys
+}
+
+// shardedTx 跨分库事务保护,事务在首次路由到的分库上开启
+type shardedTx struct {
+	ctx       context.Context
+	opt       *sql.TxOptions
+	lock      sync.Mutex
+	shardKey  string
+	tx        *sql.Tx
+	txHandler Handler
+	state     *txState // 提交/回滚钩子,事务句柄共享
+}
+
+func (st *shardedTx) handler(h *ShardedHandler, shardKey string) (handler Handler, err error) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if st.txHandler != nil {
+		if st.shardKey != shardKey {
+			err = errors.WithMessagef(ErrShardedTransaction, "transaction started on shard:%s,got shard:%s", st.shardKey, shardKey)
+			return nil, err
+		}
+		return st.txHandler, nil
+	}
+	shardHandler, err := h.Shard(shardKey)
+	if err != nil {
+		return nil, err
+	}
+	db := shardHandler.GetSqlDBHandler()()
+	tx, err := db.BeginTx(st.ctx, st.opt)
+	if err != nil {
+		return nil, err
+	}
+	st.shardKey, st.tx, st.txHandler = shardKey, tx, _TxHandler{db: db, tx: tx, state: st.state}
+	return st.txHandler, nil
+}
+
+// pinned 事务已开启时返回事务句柄,原始sql只能在事务分库上执行
+func (h *ShardedHandler) pinned() (handler Handler, err error) {
+	if h.tx != nil {
+		h.tx.lock.Lock()
+		defer h.tx.lock.Unlock()
+		if h.tx.txHandler != nil {
+			return h.tx.txHandler, nil
+		}
+	}
+	err = errors.WithMessage(ErrShardedHandlerRoute, "raw sql can not be routed, use ShardedHandler.Shard or builder with fields")
+	return nil, err
+}
+
+// Transaction 事务在首次路由到的分库上开启,事务内路由到其它分库时返回 ErrShardedTransaction
+func (h *ShardedHandler) Transaction(fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
+	return h.TransactionContext(context.Background(), fc, opts...)
+}
+
+// TransactionContext 同 Transaction,分库事务使用 ctx 开启,ctx 取消时事务回滚
+func (h *ShardedHandler) TransactionContext(ctx context.Context, fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
+	if h.tx != nil { // 嵌套事务:已在分库开启事务时使用保存点,否则直接复用
+		h.tx.lock.Lock()
+		txHandler := h.tx.txHandler
+		h.tx.lock.Unlock()
+		if txHandler == nil {
+			return fc(h)
+		}
+		return savepointTransaction(h.tx.tx, h.tx.state, func() error {
+			return fc(h)
+		})
+	}
+	var opt *sql.TxOptions
+	for i := range opts {
+		opt = opts[i]
+	}
+	txHandler := &ShardedHandler{
+		handlers: h.handlers,
+		keys:     h.keys,
+		routeFn:  h.routeFn,
+		tx:       &shardedTx{ctx: ctx, opt: opt, state: newTxState()},
+	}
+	err = fc(txHandler)
+	tx := txHandler.tx.tx
+	if tx == nil { // 未路由到任何分库,没有开启事务
+		if err != nil {
+			txHandler.tx.state.rolledBack()
+			return err
+		}
+		txHandler.tx.state.committed()
+		return nil
+	}
+	return finishTx(tx, txHandler.tx.state, err)
+}
+
+// AfterCommit 事务中注册提交后钩子
+func (h *ShardedHandler) AfterCommit(fn func()) {
+	if h.tx != nil {
+		h.tx.state.addAfterCommit(fn)
+	}
+}
+
+// AfterRollback 事务中注册回滚后钩子
+func (h *ShardedHandler) AfterRollback(fn func()) {
+	if h.tx != nil {
+		h.tx.state.addAfterRollback(fn)
+	}
+}
+
+// firstHandler 构造时已确保至少包含一个分库
+func (h *ShardedHandler) firstHandler() Handler {
+	return h.handlers[h.keys[0]]
+}
+
+// GetDialector 各分库驱动需一致
+func (h *ShardedHandler) GetDialector() string {
+	return h.firstHandler().GetDialector()
+}
+
+func (h *ShardedHandler) GetSqlDBHandler() SqlDBHandler {
+	return h.firstHandler().GetSqlDBHandler()
+}
+
+func (h *ShardedHandler) OriginalHandler() Handler {
+	return h
+}
+
+func (h *ShardedHandler) IsOriginalHandler() bool {
+	return true
+}
+
+func (h *ShardedHandler) Exec(sql string) (err error) {
+	handler, err := h.pinned()
+	if err != nil {
+		return err
+	}
+	return handler.Exec(sql)
+}
+
+func (h *ShardedHandler) ExecWithRowsAffected(sql string) (rowsAffected int64, err error) {
+	handler, err := h.pinned()
+	if err != nil {
+		return 0, err
+	}
+	return handler.ExecWithRowsAffected(sql)
+}
+
+func (h *ShardedHandler) InsertWithLastId(sql string) (lastInsertId uint64, rowsAffected int64, err error) {
+	handler, err := h.pinned()
+	if err != nil {
+		return 0, 0, err
+	}
+	return handler.InsertWithLastId(sql)
+}
+
+func (h *ShardedHandler) First(ctx context.Context, sql string, result any) (exists bool, err error) {
+	handler, err := h.pinned()
+	if err != nil {
+		return false, err
+	}
+	return handler.First(ctx, sql, result)
+}
+
+func (h *ShardedHandler) Query(ctx context.Context, sql string, result any) (err error) {
+	handler, err := h.pinned()
+	if err != nil {
+		return err
+	}
+	return handler.Query(ctx, sql, result)
+}
+
+func (h *ShardedHandler) Count(sql string) (count int64, err error) {
+	handler, err := h.pinned()
+	if err != nil {
+		return 0, err
+	}
+	return handler.Count(sql)
+}
+
+func (h *ShardedHandler) Exists(sql string) (exists bool, err error) {
+	handler, err := h.pinned()
+	if err != nil {
+		return false, err
+	}
+	return handler.Exists(sql)
+}
+
+func isShardedHandler(handler Handler) bool {
+	_, ok := handler.(*ShardedHandler)
+	return ok
+}
+
+// routeShardedHandlers 表句柄为分库句柄时,按字段路由到具体分库,每个分库生成一份表配置
+func routeShardedHandlers(tables []TableConfig, fs Fields) (routed []TableConfig, err error) {
+	for _, table := range tables {
+		shardedHandler, ok := table._handler.(*ShardedHandler)
+		if !ok {
+			routed = append(routed, table)
+			continue
+		}
+		handlers, err := shardedHandler.Route(fs)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "table:%s", table.Name)
+		}
+		for _, handler := range handlers {
+			routed = append(routed, table.WithHandler(handler))
+		}
+	}
+	return routed, nil
+}
+
+// shardedList 列表查询在所有路由到的分库/分表上执行,排序、分页在合并后生效:各分库/分表取前 offset+limit 条,按排序多路归并后再截取
+func (p ListParam) shardedList(result any) (err error) {
+	rv := reflect.Indirect(reflect.ValueOf(result))
+	if rv.Kind() != reflect.Slice || !rv.CanSet() {
+		err = errors.Errorf("sharded list result must be slice pointer,got:%T", result)
+		return err
+	}
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return err
+	}
+	firstP := p
+	firstP._Table = tables[0]
+	ds, err := firstP.makeSelectDataset(p._Fields)
+	if err != nil {
+		return err
+	}
+	clauses := ds.GetClauses()
+	orderedExpressions := make([]exp.OrderedExpression, 0)
+	if order := clauses.Order(); order != nil {
+		for _, expression := range order.Columns() {
+			orderedExpression, ok := expression.(exp.OrderedExpression)
+			if !ok {
+				err = errors.Errorf("sharded list table:%s unsupported order expression %T", p._Table.Name, expression)
+				return err
+			}
+			orderedExpressions = append(orderedExpressions, orderedExpression)
+		}
+	}
+	orderKeys, err := newShardedOrderKeys(p._Table, orderedExpressions)
+	if err != nil {
+		err = errors.WithMessage(err, "sharded list use ShardedTablePaginationParam.WithUnion instead")
+		return err
+	}
+	offset, need := int64(clauses.Offset()), int64(-1)
+	if limit := clauses.Limit(); limit != nil {
+		size, ok := limit.(uint)
+		if !ok {
+			err = errors.Errorf("sharded list table:%s unsupported limit expression %T", p._Table.Name, limit)
+			return err
+		}
+		need = offset + int64(size)
+	}
+	subResults := make([]reflect.Value, 0, len(tables))
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		shardedP.builderFns = append(slices.Clone(p.builderFns), func(ds *goqu.SelectDataset) *goqu.SelectDataset {
+			ds = ds.ClearOffset()
+			if need >= 0 {
+				ds = ds.Limit(uint(need))
+			}
+			return ds
+		})
+		subResult := reflect.New(rv.Type())
+		err = shardedP.List(subResult.Interface())
+		if err != nil {
+			return err
+		}
+		subResults = append(subResults, subResult.Elem())
+	}
+	rv.Set(orderKeys.merge(rv.Type(), subResults, offset, need))
+	return nil
+}
+
+// shardedCount 统计所有路由到的分库/分表总数
+func (p TotalParam) shardedCount() (total int64, err error) {
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return 0, err
+	}
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		subTotal, err := shardedP.Count()
+		if err != nil {
+			return 0, err
+		}
+		total += subTotal
+	}
+	return total, nil
+}
+
+// shardedExists 按分库/分表顺序查询,任一存在即返回
+func (p ExistsParam) shardedExists() (exists bool, err error) {
+	tables, err := p._Table.routeShardedTables(p._Fields)
+	if err != nil {
+		return false, err
+	}
+	for _, table := range tables {
+		shardedP := p
+		shardedP._Table = table
+		exists, err = shardedP.Exists()
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isSharded 分表或分库
+func (t TableConfig) isSharded() bool {
+	return t.isShardedTable() || isShardedHandler(t._handler)
+}
+
+var _ Handler = (*ShardedHandler)(nil)
@@ -0,0 +1,140 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func TestShardedHandler(t *testing.T) {
+	handlers := map[string]sqlbuilder.Handler{}
+	dbs := map[string]*sql.DB{}
+	for _, shardKey := range []string{"db0", "db1"} {
+		db := sqlbuildertest.New(t, newUserTableConfig("sharded_db_user"))
+		dbs[shardKey] = db.SqlDB()
+		handlers[shardKey] = db.Handler()
+	}
+	// 按id奇偶分库
+	shardedHandler, err := sqlbuilder.NewShardedHandler(handlers, func(fs sqlbuilder.Fields) (shardKeys []string) {
+		f, ok := fs.GetByName("id")
+		if !ok {
+			return nil
+		}
+		val, _ := f.GetValue(sqlbuilder.Layer_get_value_before_db)
+		if id := cast.ToInt(val); id > 0 {
+			return []string{fmt.Sprintf("db%d", id%2)}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	table := newUserTableConfig("sharded_db_user").WithHandler(shardedHandler)
+	for _, id := range []int{1, 2, 3, 4} {
+		_, _, err := sqlbuilder.NewInsertBuilder(table).AppendFields(NewIterateUserId(id), NewIterateUserName(fmt.Sprintf("user%d", id))).Insert()
+		require.NoError(t, err)
+	}
+	var count int
+	require.NoError(t, dbs["db1"].QueryRow("select count(*) from sharded_db_user").Scan(&count))
+	require.Equal(t, 2, count)
+
+	t.Run("fan-out read", func(t *testing.T) {
+		total, err := sqlbuilder.NewTotalBuilder(table).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 4, total)
+
+		order := NewIterateUserId(0).SetOrderFn(sqlbuilder.OrderFnDesc)
+		users := make([]iterateUser, 0)
+		total, err = sqlbuilder.NewPaginationBuilder(table).AppendFields(order, NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(3).SetTag(sqlbuilder.Field_tag_pageSize)).Pagination(&users)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, total)
+		require.Equal(t, []iterateUser{{Id: 4, Name: "user4"}, {Id: 3, Name: "user3"}, {Id: 2, Name: "user2"}}, users)
+	})
+
+	t.Run("list order and limit across shards", func(t *testing.T) {
+		users := make([]iterateUser, 0)
+		order := NewIterateUserId(0).SetOrderFn(sqlbuilder.OrderFnDesc)
+		err := sqlbuilder.NewListBuilder(table).AppendFields(order, NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(3).SetTag(sqlbuilder.Field_tag_pageSize)).List(&users)
+		require.NoError(t, err)
+		require.Equal(t, []iterateUser{{Id: 4, Name: "user4"}, {Id: 3, Name: "user3"}, {Id: 2, Name: "user2"}}, users)
+
+		users = make([]iterateUser, 0)
+		err = sqlbuilder.NewListBuilder(table).AppendFields(order, NewPageIndex(1).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(3).SetTag(sqlbuilder.Field_tag_pageSize)).List(&users)
+		require.NoError(t, err)
+		require.Equal(t, []iterateUser{{Id: 1, Name: "user1"}}, users)
+
+		users = make([]iterateUser, 0)
+		err = sqlbuilder.NewListBuilder(table).List(&users) // 默认按主键升序
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2, 3, 4}, []int{users[0].Id, users[1].Id, users[2].Id, users[3].Id})
+	})
+
+	t.Run("transaction with canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := shardedHandler.TransactionContext(ctx, func(tx sqlbuilder.Handler) error {
+			_, _, err := sqlbuilder.NewInsertBuilder(table.WithHandler(tx)).AppendFields(NewIterateUserId(7), NewIterateUserName("user7")).Insert()
+			return err
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("transaction spans shards", func(t *testing.T) {
+		err := table.Repository().Transaction(func(txRepository sqlbuilder.Repository) (err error) {
+			err = txRepository.Insert(context.Background(), sqlbuilder.Fields{NewIterateUserId(5), NewIterateUserName("user5")})
+			if err != nil {
+				return err
+			}
+			return txRepository.Insert(context.Background(), sqlbuilder.Fields{NewIterateUserId(6), NewIterateUserName("user6")})
+		})
+		require.ErrorIs(t, err, sqlbuilder.ErrShardedTransaction)
+		exists, err := sqlbuilder.NewExistsBuilder(table).AppendFields(NewIterateUserId(5).AppendWhereFn(sqlbuilder.ValueFnForward)).Exists()
+		require.NoError(t, err)
+		require.False(t, exists) // 已回滚
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		exists, err := sqlbuilder.NewExistsBuilder(table).AppendFields(NewIterateUserId(3).AppendWhereFn(sqlbuilder.ValueFnForward)).Exists()
+		require.NoError(t, err)
+		require.True(t, exists)
+		exists, err = sqlbuilder.NewExistsBuilder(table).AppendFields(NewIterateUserName("user4").AppendWhereFn(sqlbuilder.ValueFnForward)).Exists() // 未指定分库键,所有分库查询
+		require.NoError(t, err)
+		require.True(t, exists)
+	})
+
+	t.Run("invalid handlers", func(t *testing.T) {
+		_, err := sqlbuilder.NewShardedHandler(nil, nil)
+		require.ErrorIs(t, err, sqlbuilder.ErrShardedHandlerRoute)
+		_, err = sqlbuilder.NewShardedHandler(map[string]sqlbuilder.Handler{"db0": nil}, nil)
+		require.ErrorIs(t, err, sqlbuilder.ErrShardedHandlerRoute)
+	})
+}
+
+func TestShardedHandlerTotalCache(t *testing.T) {
+	handlers := map[string]sqlbuilder.Handler{}
+	for i, shardKey := range []string{"db0", "db1"} {
+		db := sqlbuildertest.New(t, newUserTableConfig("sharded_cache_user"))
+		rows := make([]map[string]any, 0)
+		for id := 1; id <= i+1; id++ { // db0 1条,db1 2条,各库统计 sql 相同
+			rows = append(rows, map[string]any{"id": id, "name": fmt.Sprintf("user%d", id)})
+		}
+		db.InsertFixtures(sqlbuildertest.Fixtures{"sharded_cache_user": rows})
+		handlers[shardKey] = db.Handler()
+	}
+	shardedHandler, err := sqlbuilder.NewShardedHandler(handlers, func(fs sqlbuilder.Fields) (shardKeys []string) { return nil })
+	require.NoError(t, err)
+	table := newUserTableConfig("sharded_cache_user").WithHandler(shardedHandler)
+	for range 2 { // 第二次命中缓存
+		users := make([]iterateUser, 0)
+		paginationBuilder := sqlbuilder.NewPaginationBuilder(table).AppendFields(NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(10).SetTag(sqlbuilder.Field_tag_pageSize))
+		total, err := sqlbuilder.NewShardedTablePaginationBuilder(*paginationBuilder).WithTotalCacheDuration(time.Minute).Pagination(&users)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, total)
+		require.Len(t, users, 3)
+	}
+}
@@ -0,0 +1,214 @@
+package sqlbuilder
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ErrNotInTransaction 句柄不是事务句柄,无法注册事务钩子
+var ErrNotInTransaction = errors.New("handler not in transaction")
+
+// TxHooker 事务句柄实现,注册最外层事务提交、回滚后执行的钩子;
+// 嵌套事务(保存点)回滚时,其内注册的提交钩子被丢弃,回滚钩子立即执行
+type TxHooker interface {
+	AfterCommit(fn func())
+	AfterRollback(fn func())
+}
+
+// RegisterAfterCommit 在事务句柄上注册提交后钩子
+func RegisterAfterCommit(tx Handler, fn func()) (err error) {
+	hooker, ok := tx.(TxHooker)
+	if !ok || !inTransaction(tx) {
+		return ErrNotInTransaction
+	}
+	hooker.AfterCommit(fn)
+	return nil
+}
+
+// RegisterAfterRollback 在事务句柄上注册回滚后钩子
+func RegisterAfterRollback(tx Handler, fn func()) (err error) {
+	hooker, ok := tx.(TxHooker)
+	if !ok || !inTransaction(tx) {
+		return ErrNotInTransaction
+	}
+	hooker.AfterRollback(fn)
+	return nil
+}
+
+// inTransaction 分库句柄实现了 TxHooker,需要判断是否在事务中
+func inTransaction(handler Handler) bool {
+	if shardedHandler, ok := handler.(*ShardedHandler); ok {
+		return shardedHandler.tx != nil
+	}
+	return true
+}
+
+// txState 同一个数据库事务(含嵌套保存点)共享的状态
+type txState struct {
+	lock           sync.Mutex
+	savepointSeq   int
+	afterCommits   []func()
+	afterRollbacks []func()
+}
+
+// txMark 保存点开始时钩子数量,保存点回滚时据此丢弃其内注册的钩子
+type txMark struct {
+	afterCommits   int
+	afterRollbacks int
+}
+
+func newTxState() *txState {
+	return &txState{}
+}
+
+func (s *txState) addAfterCommit(fn func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.afterCommits = append(s.afterCommits, fn)
+}
+
+func (s *txState) addAfterRollback(fn func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.afterRollbacks = append(s.afterRollbacks, fn)
+}
+
+// mark 记录当前钩子位置
+func (s *txState) mark() txMark {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return txMark{afterCommits: len(s.afterCommits), afterRollbacks: len(s.afterRollbacks)}
+}
+
+// nextSavepoint 生成保存点名称并记录钩子位置
+func (s *txState) nextSavepoint() (name string, mark txMark) {
+	s.lock.Lock()
+	s.savepointSeq++
+	name = fmt.Sprintf("sp_%d", s.savepointSeq)
+	s.lock.Unlock()
+	return name, s.mark()
+}
+
+// rollbackTo 保存点回滚:执行其内注册的回滚钩子,丢弃其内注册的全部钩子
+func (s *txState) rollbackTo(mark txMark) {
+	s.lock.Lock()
+	fns := append([]func(){}, s.afterRollbacks[mark.afterRollbacks:]...)
+	s.afterCommits = s.afterCommits[:mark.afterCommits]
+	s.afterRollbacks = s.afterRollbacks[:mark.afterRollbacks]
+	s.lock.Unlock()
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
+// committed 最外层事务提交后按注册顺序执行提交钩子
+func (s *txState) committed() {
+	s.lock.Lock()
+	fns := s.afterCommits
+	s.afterCommits, s.afterRollbacks = nil, nil
+	s.lock.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// rolledBack 最外层事务回滚后按注册逆序执行回滚钩子
+func (s *txState) rolledBack() {
+	s.lock.Lock()
+	fns := s.afterRollbacks
+	s.afterCommits, s.afterRollbacks = nil, nil
+	s.lock.Unlock()
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
+// finishTx 提交或回滚最外层事务并执行钩子
+func finishTx(tx *sql.Tx, state *txState, err error) error {
+	if err != nil {
+		_ = tx.Rollback()
+		state.rolledBack()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		state.rolledBack()
+		return err
+	}
+	state.committed()
+	return nil
+}
+
+// savepointTransaction 在已开启的事务中以保存点执行嵌套事务,mysql、sqlite 语法一致
+func savepointTransaction(tx *sql.Tx, state *txState, fc func() error) (err error) {
+	name, mark := state.nextSavepoint()
+	if _, err = tx.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+	err = fc()
+	if err != nil {
+		if _, rollbackErr := tx.Exec("ROLLBACK TO SAVEPOINT " + name); rollbackErr != nil {
+			return errors.WithMessagef(err, "rollback to savepoint:%s failed:%s", name, rollbackErr.Error())
+		}
+		_, _ = tx.Exec("RELEASE SAVEPOINT " + name) // sqlite 回滚到保存点后保存点仍在栈中,需释放
+		state.rollbackTo(mark)
+		return err
+	}
+	if _, err = tx.Exec("RELEASE SAVEPOINT " + name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// _GormTxHandler gorm 事务句柄,嵌套事务由gorm 使用保存点实现
+type _GormTxHandler struct {
+	GormHandler
+	state *txState
+}
+
+func newGormTxHandler(tx *gorm.DB, state *txState) _GormTxHandler {
+	return _GormTxHandler{
+		GormHandler: GormHandler(func() *gorm.DB { return tx }),
+		state:       state,
+	}
+}
+
+func (h _GormTxHandler) Transaction(fc func(tx Handler) error, opts ...*sql.TxOptions) (err error) {
+	mark := h.state.mark()
+	err = h.GormHandler().Transaction(func(tx *gorm.DB) error {
+		return fc(newGormTxHandler(tx, h.state))
+	}, opts...)
+	if err != nil {
+		h.state.rollbackTo(mark)
+		return err
+	}
+	return nil
+}
+
+func (h _GormTxHandler) OriginalHandler() Handler {
+	return h
+}
+
+func (h _GormTxHandler) AfterCommit(fn func()) {
+	h.state.addAfterCommit(fn)
+}
+
+func (h _GormTxHandler) AfterRollback(fn func()) {
+	h.state.addAfterRollback(fn)
+}
+
+func (h _TxHandler) AfterCommit(fn func()) {
+	h.state.addAfterCommit(fn)
+}
+
+func (h _TxHandler) AfterRollback(fn func()) {
+	h.state.addAfterRollback(fn)
+}
+
+var _ TxHooker = _TxHandler{}
+var _ TxHooker = _GormTxHandler{}
+var _ TxHooker = (*ShardedHandler)(nil)
@@ -0,0 +1,87 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+)
+
+func TestNestedTransaction(t *testing.T) {
+	ctx := context.Background()
+	table := newIterateTable(t, 0)
+	errInner := errors.New("inner failed")
+	hooks := make([]string, 0)
+	err := table.Repository().Transaction(func(txRepository sqlbuilder.Repository) (err error) {
+		txHandler := txRepository.GetTable().GetHandler()
+		require.NoError(t, sqlbuilder.RegisterAfterCommit(txHandler, func() { hooks = append(hooks, "outer commit") }))
+		err = txRepository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(1), NewIterateUserName("user1")})
+		if err != nil {
+			return err
+		}
+		err = txRepository.Transaction(func(innerRepository sqlbuilder.Repository) (err error) {
+			innerHandler := innerRepository.GetTable().GetHandler()
+			require.NoError(t, sqlbuilder.RegisterAfterCommit(innerHandler, func() { hooks = append(hooks, "inner commit") }))
+			require.NoError(t, sqlbuilder.RegisterAfterRollback(innerHandler, func() { hooks = append(hooks, "inner rollback") }))
+			err = innerRepository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(2), NewIterateUserName("user2")})
+			if err != nil {
+				return err
+			}
+			return errInner
+		})
+		require.ErrorIs(t, err, errInner)
+		return txRepository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(3), NewIterateUserName("user3")})
+	}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	require.NoError(t, err)
+	require.Equal(t, []string{"inner rollback", "outer commit"}, hooks)
+
+	users := make([]iterateUser, 0)
+	err = sqlbuilder.NewListBuilder(table).AppendFields(NewIterateUserId(0).SetOrderFn(sqlbuilder.OrderFnAsc)).List(&users)
+	require.NoError(t, err)
+	require.Equal(t, []iterateUser{{Id: 1, Name: "user1"}, {Id: 3, Name: "user3"}}, users)
+
+	t.Run("outer rollback", func(t *testing.T) {
+		rollbacked := false
+		err := table.Repository().Transaction(func(txRepository sqlbuilder.Repository) (err error) {
+			require.NoError(t, sqlbuilder.RegisterAfterRollback(txRepository.GetTable().GetHandler(), func() { rollbacked = true }))
+			err = txRepository.Transaction(func(innerRepository sqlbuilder.Repository) (err error) {
+				return innerRepository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(4), NewIterateUserName("user4")})
+			})
+			require.NoError(t, err)
+			return errInner
+		})
+		require.ErrorIs(t, err, errInner)
+		require.True(t, rollbacked)
+		total, err := sqlbuilder.NewTotalBuilder(table).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+	})
+
+	t.Run("gorm", func(t *testing.T) {
+		sqlDB := table.GetHandler().GetSqlDBHandler()
+		gormTable := table.WithHandler(sqlbuilder.NewGormHandler(sqlbuilder.DB2Gorm(sqlDB, nil)))
+		committed := false
+		err := gormTable.Repository().Transaction(func(txRepository sqlbuilder.Repository) (err error) {
+			require.NoError(t, sqlbuilder.RegisterAfterCommit(txRepository.GetTable().GetHandler(), func() { committed = true }))
+			err = txRepository.Transaction(func(innerRepository sqlbuilder.Repository) (err error) {
+				err = innerRepository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(5), NewIterateUserName("user5")})
+				if err != nil {
+					return err
+				}
+				return errInner
+			})
+			require.ErrorIs(t, err, errInner)
+			return txRepository.Insert(ctx, sqlbuilder.Fields{NewIterateUserId(6), NewIterateUserName("user6")})
+		})
+		require.NoError(t, err)
+		require.True(t, committed)
+		total, err := sqlbuilder.NewTotalBuilder(table).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 3, total)
+	})
+
+	require.ErrorIs(t, sqlbuilder.RegisterAfterCommit(table.GetHandler(), func() {}), sqlbuilder.ErrNotInTransaction)
+}
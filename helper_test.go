@@ -0,0 +1,71 @@
+package sqlbuilder_test
+
+// 多个测试文件共用的字段、表定义及测试库
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func NewIterateUserId(id int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(id, "id", "ID", 0)
+}
+
+func NewIterateUserName(name string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(name, "name", "名称", 64)
+}
+
+func NewNullUserNickname(nickname string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(nickname, "nickname", "昵称", 64)
+}
+
+func NewFkDeletedAt(deletedAt string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(deletedAt, sqlbuilder.Field_name_deletedAt, "删除时间", 0)
+}
+
+func NewRelOrderId(orderId int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(orderId, "orderId", "订单ID", 0)
+}
+
+func NewSubQueryCustomerId(customerId int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(customerId, "customerId", "客户ID", 0)
+}
+
+// fkPrimaryIndex id 列主键
+var fkPrimaryIndex = sqlbuilder.Index{
+	IsPrimary: true,
+	ColumnNames: func(table sqlbuilder.TableConfig) (columnNames []string) {
+		return []string{"id"}
+	},
+}
+
+type iterateUser struct {
+	Id   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// newUserTableConfig id、name 两列的用户表,分表测试按表名创建多张
+func newUserTableConfig(name string) sqlbuilder.TableConfig {
+	return sqlbuilder.NewTableConfig(name).AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+	).AddIndexs(fkPrimaryIndex)
+}
+
+var iterateUserTable = newUserTableConfig("iterate_user")
+
+// newIterateTable iterate_user 表测试库,写入 rowCount 条记录 id:i,name:user{i}
+func newIterateTable(t *testing.T, rowCount int) sqlbuilder.TableConfig {
+	db := sqlbuildertest.New(t, iterateUserTable)
+	if rowCount > 0 {
+		rows := make([]map[string]any, 0, rowCount)
+		for i := 1; i <= rowCount; i++ {
+			rows = append(rows, map[string]any{"id": i, "name": fmt.Sprintf("user%d", i)})
+		}
+		db.InsertFixtures(sqlbuildertest.Fixtures{"iterate_user": rows})
+	}
+	return db.Table("iterate_user")
+}
@@ -0,0 +1,454 @@
+package sqlbuilder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidRequest 请求参数、请求体格式错误或校验失败
+var ErrInvalidRequest = errors.New("invalid request")
+
+var (
+	RestHandler_defaultPageSize = 20
+	RestHandler_maxPageSize     = 100
+)
+
+// RestHandler 由 TableConfig 生成的 REST 增删改查 net/http 处理器,挂载时去掉路由前缀,如 mux.Handle("/users/", http.StripPrefix("/users", h)):
+//
+//	GET    /      列表,参数 pageIndex(从0开始)、pageSize、filter(见 ParseFilters)、sort(见 ParseSort)、fields(逗号分隔查询列),返回 {"items":[],"total":0}
+//	GET    /{id}  按主键查询
+//	POST   /      新增,返回新增记录
+//	PATCH  /{id}  按 JSON merge-patch 语义更新(未出现字段不更新,null 写入 NULL),返回更新后记录
+//	DELETE /{id}  按主键软删除,表无 deletedAt 列时返回 405
+//
+// 表需为单列主键;请求体、返回记录的键为 Field.Name,仅白名单 fields 中的字段可写入、过滤、排序、查询及返回;存在 deletedAt 列时查询、更新忽略已删除记录;
+// 错误返回 {"error":{"code":"","message":""}}
+type RestHandler struct {
+	table       TableConfig
+	fields      Fields
+	defaultSort string
+	mux         *http.ServeMux
+}
+
+func NewRestHandler(table TableConfig, fields Fields) *RestHandler {
+	h := &RestHandler{table: table, fields: fields}
+	h.mux = http.NewServeMux()
+	h.mux.HandleFunc("GET /{$}", h.list)
+	h.mux.HandleFunc("POST /{$}", h.create)
+	h.mux.HandleFunc("GET /{id}", h.get)
+	h.mux.HandleFunc("PATCH /{id}", h.patch)
+	deleteFn := h.delete
+	if _, ok := table.Columns.GetByFieldName(Field_name_deletedAt); !ok { // 不支持物理删除
+		deleteFn = methodNotAllowed
+	}
+	h.mux.HandleFunc("DELETE /{id}", deleteFn)
+	return h
+}
+
+// WithDefaultSort 列表未指定 sort 参数时的排序
+func (h *RestHandler) WithDefaultSort(defaultSort string) *RestHandler {
+	h.defaultSort = defaultSort
+	return h
+}
+
+func (h *RestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *RestHandler) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pageIndex, err := restQueryInt(query, "pageIndex", 0)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	pageSize, err := restQueryInt(query, "pageSize", RestHandler_defaultPageSize)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	filters, err := ParseFilters(query.Get("filter"))
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	fs, err := filters.Fields(h.fields)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	fs = append(fs, h.notDeletedFields()...)
+	fs = append(fs,
+		NewIntField(max(pageIndex, 0), Field_tag_pageIndex, "页码", 0).SetTag(Field_tag_pageIndex),
+		NewIntField(min(max(pageSize, 1), RestHandler_maxPageSize), Field_tag_pageSize, "每页数量", 0).SetTag(Field_tag_pageSize),
+	)
+	names, err := h.projection(query.Get("fields"))
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	rows := make([]map[string]any, 0)
+	total, err := NewPaginationBuilder(h.table).WithContext(r.Context()).AppendFields(fs...).
+		WithSort(query.Get("sort"), h.fields, h.defaultSort).WithProjection(names...).Pagination(&rows)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	items := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, h.toRecord(row))
+	}
+	writeRestJSON(w, http.StatusOK, map[string]any{"items": items, "total": total})
+}
+
+func (h *RestHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := h.primaryValue(r.PathValue("id"))
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	record, err := h.first(r.Context(), id)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	writeRestJSON(w, http.StatusOK, record)
+}
+
+func (h *RestHandler) create(w http.ResponseWriter, r *http.Request) {
+	fs, err := h.decodeBody(r, true)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	lastInsertId, _, err := h.table.Repository().InsertWithLastId(r.Context(), fs)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	primaryCol, err := h.primaryColumn()
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	var id any = lastInsertId
+	if f, ok := fs.GetByName(primaryCol.FieldName); ok { // 请求体指定主键
+		id = f.GetOriginalValue()
+	}
+	record, err := h.first(r.Context(), id)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	writeRestJSON(w, http.StatusCreated, record)
+}
+
+func (h *RestHandler) patch(w http.ResponseWriter, r *http.Request) {
+	id, err := h.primaryValue(r.PathValue("id"))
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	fs, err := h.decodeBody(r, false)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	_, err = h.first(r.Context(), id) // 不存在返回404,同时避免更新已删除记录
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	if len(fs) > 0 {
+		where, err := h.primaryWhereField(id)
+		if err != nil {
+			writeRestError(w, err)
+			return
+		}
+		fs = append(fs, where.ShieldUpdate(true))
+		_, err = h.table.Repository().UpdateWithRowsAffected(r.Context(), fs)
+		if err != nil {
+			writeRestError(w, err)
+			return
+		}
+	}
+	record, err := h.first(r.Context(), id)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	writeRestJSON(w, http.StatusOK, record)
+}
+
+func (h *RestHandler) delete(w http.ResponseWriter, r *http.Request) {
+	deletedAtCol, err := h.table.Columns.GetByFieldNameAsError(Field_name_deletedAt)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	id, err := h.primaryValue(r.PathValue("id"))
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	_, err = h.first(r.Context(), id)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	where, err := h.primaryWhereField(id)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	deletedAt := deletedAtCol.MakeField(time.Now().Format(time.DateTime)).SetFieldName(deletedAtCol.FieldName)
+	err = h.table.Repository().Delete(r.Context(), Fields{where, deletedAt})
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RestHandler) primaryColumn() (col ColumnConfig, err error) {
+	primary, ok := h.table.Indexs.GetPrimary()
+	if !ok {
+		err = errors.Errorf("RestHandler table:%s primary key not found", h.table.Name)
+		return col, err
+	}
+	columnNames := primary.ColumnNames(h.table)
+	if len(columnNames) != 1 {
+		err = errors.Errorf("RestHandler table:%s required single column primary key,got:%v", h.table.Name, columnNames)
+		return col, err
+	}
+	col, ok = h.table.Columns.GetByDbName(columnNames[0])
+	if !ok {
+		err = errors.Errorf("RestHandler table:%s primary column:%s not found", h.table.Name, columnNames[0])
+		return col, err
+	}
+	return col, nil
+}
+
+// primaryValue 路径参数按主键字段类型转换
+func (h *RestHandler) primaryValue(id string) (value any, err error) {
+	col, err := h.primaryColumn()
+	if err != nil {
+		return nil, err
+	}
+	value, err = convertBySchemaType(col.GetField(), id)
+	if err != nil {
+		err = errors.WithMessage(ErrInvalidRequest, err.Error())
+		return nil, err
+	}
+	return value, nil
+}
+
+func (h *RestHandler) primaryWhereField(id any) (f *Field, err error) {
+	col, err := h.primaryColumn()
+	if err != nil {
+		return nil, err
+	}
+	return col.MakeField(id).AppendWhereFn(ValueFnForward), nil
+}
+
+func (h *RestHandler) notDeletedFields() Fields {
+	if deletedAtCol, ok := h.table.Columns.GetByFieldName(Field_name_deletedAt); ok {
+		return Fields{newNotDeletedField(deletedAtCol)}
+	}
+	return nil
+}
+
+// first 按主键查询记录,不存在返回 ErrNotFound
+func (h *RestHandler) first(ctx context.Context, id any) (record map[string]any, err error) {
+	where, err := h.primaryWhereField(id)
+	if err != nil {
+		return nil, err
+	}
+	fs := append(Fields{where}, h.notDeletedFields()...)
+	row := make(map[string]any)
+	exists, err := NewFirstBuilder(h.table).WithContext(ctx).AppendFields(fs...).First(&row)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		err = errors.WithMessagef(ErrNotFound, "table:%s id:%v", h.table.Name, id)
+		return nil, err
+	}
+	return h.toRecord(row), nil
+}
+
+// decodeBody 解析 json 请求体为字段,键为白名单 Field.Name,值按字段 Schema 类型转换并校验;新增时校验必填字段,
+// 更新时按 JSON merge-patch 语义解析(见 MergePatchFields)且不可修改主键
+func (h *RestHandler) decodeBody(r *http.Request, isCreate bool) (fs Fields, err error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err = errors.WithMessagef(ErrInvalidRequest, "read body:%s", err.Error())
+		return nil, err
+	}
+	if isCreate {
+		fs, err = h.decodeCreateBody(body)
+	} else {
+		fs, err = h.decodePatchBody(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = fs.Validate()
+	if errors.Is(err, ErrNullNotAllowed) { // 与写入不可为空列一致,返回 422
+		return nil, err
+	}
+	if err != nil {
+		err = errors.WithMessage(ErrInvalidRequest, err.Error())
+		return nil, err
+	}
+	return fs, nil
+}
+
+// decodeCreateBody 新增请求体,值为 null 的键忽略,校验必填字段
+func (h *RestHandler) decodeCreateBody(body []byte) (fs Fields, err error) {
+	data := make(map[string]any)
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		err = errors.WithMessagef(ErrInvalidRequest, "decode json body:%s", err.Error())
+		return nil, err
+	}
+	fs = make(Fields, 0, len(data))
+	for name, v := range data {
+		allowed, ok := h.fields.GetByName(name)
+		if !ok {
+			err = errors.WithMessagef(ErrInvalidRequest, "field:%s not allowed", name)
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		value, err := convertBySchemaType(allowed, v)
+		if err != nil {
+			err = errors.WithMessage(ErrInvalidRequest, err.Error())
+			return nil, err
+		}
+		fs = append(fs, allowed.Copy().SetValue(value))
+	}
+	for _, f := range h.fields {
+		if f.Schema == nil || !f.Schema.Required {
+			continue
+		}
+		if _, ok := fs.GetByName(f.Name); !ok {
+			err = errors.WithMessagef(ErrInvalidRequest, "%s is required", f.Name)
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+// decodePatchBody 更新请求体,不可修改主键
+func (h *RestHandler) decodePatchBody(body []byte) (fs Fields, err error) {
+	fs, err = MergePatchFields(body, h.fields)
+	if err != nil {
+		return nil, err
+	}
+	primaryCol, err := h.primaryColumn()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := fs.GetByName(primaryCol.FieldName); ok {
+		err = errors.WithMessagef(ErrInvalidRequest, "primary key:%s can not be updated", primaryCol.FieldName)
+		return nil, err
+	}
+	return fs, nil
+}
+
+// projection 列表 fields 参数,仅可查询白名单字段
+func (h *RestHandler) projection(fields string) (names []string, err error) {
+	if fields == "" {
+		return nil, nil
+	}
+	names = strings.Split(fields, ",")
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := h.fields.GetByName(name); !ok {
+			err = errors.WithMessagef(ErrInvalidProjection, "field:%s not allowed", name)
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// toRecord 返回记录的键由列名转换为 Field.Name,仅返回白名单字段
+func (h *RestHandler) toRecord(row map[string]any) (record map[string]any) {
+	record = make(map[string]any, len(row))
+	for key, v := range row {
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		if col, ok := h.table.Columns.GetByDbName(key); ok {
+			key = col.FieldName
+		}
+		if _, ok := h.fields.GetByName(key); !ok {
+			continue
+		}
+		record[key] = v
+	}
+	return record
+}
+
+func restQueryInt(query map[string][]string, name string, defaultValue int) (value int, err error) {
+	values := query[name]
+	if len(values) == 0 || values[0] == "" {
+		return defaultValue, nil
+	}
+	value, err = strconv.Atoi(values[0])
+	if err != nil {
+		err = errors.WithMessagef(ErrInvalidRequest, "query:%s required int,got:%s", name, values[0])
+		return 0, err
+	}
+	return value, nil
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeRestJSON(w, http.StatusMethodNotAllowed, restErrorBody("method_not_allowed", "table without deletedAt column can not be deleted"))
+}
+
+func restErrorBody(code string, message string) map[string]any {
+	return map[string]any{"error": map[string]any{"code": code, "message": message}}
+}
+
+// restErrorStatus 错误对应的 http 状态码及错误码
+func restErrorStatus(err error) (status int, code string) {
+	switch {
+	case errors.Is(err, ErrInvalidRequest), errors.Is(err, ErrInvalidFilter), errors.Is(err, ErrInvalidSort), errors.Is(err, ErrInvalidProjection), errors.Is(err, ErrInvalidMergePatch):
+		return http.StatusBadRequest, "invalid_argument"
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, ErrDuplicateKey), errors.Is(err, ErrUnique), errors.Is(err, Error_UniqueIndexAlreadyExist):
+		return http.StatusConflict, "conflict"
+	case errors.Is(err, ErrForeignKey), errors.Is(err, ErrNotNull), errors.Is(err, ErrNullNotAllowed), errors.Is(err, ErrDataTooLong):
+		return http.StatusUnprocessableEntity, "constraint_violation"
+	}
+	return http.StatusInternalServerError, "internal"
+}
+
+func writeRestError(w http.ResponseWriter, err error) {
+	status, code := restErrorStatus(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = http.StatusText(status) // 不暴露 sql 等内部信息
+	}
+	writeRestJSON(w, status, restErrorBody(code, message))
+}
+
+func writeRestJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,180 @@
+package sqlbuilder_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func NewRestUserName(name string) *sqlbuilder.Field {
+	return NewIterateUserName(name).MergeSchema(sqlbuilder.Schema{Required: true})
+}
+
+func NewRestUserPassword(password string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(password, "password", "密码", 64)
+}
+
+var restUserTable = sqlbuilder.NewTableConfig("rest_user").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewRestUserName)),
+	sqlbuilder.NewColumn("nickname", sqlbuilder.GetField(NewNullUserNickname)),
+	sqlbuilder.NewColumn("password", sqlbuilder.GetField(NewRestUserPassword)),
+	sqlbuilder.NewColumn("deleted_at", sqlbuilder.GetField(NewFkDeletedAt)),
+).AddIndexs(fkPrimaryIndex, sqlbuilder.Index{
+	Unique: true,
+	ColumnNames: func(table sqlbuilder.TableConfig) (columnNames []string) {
+		return []string{"name"}
+	},
+})
+
+func newRestServer(t *testing.T) *httptest.Server {
+	db := sqlbuildertest.New(t, restUserTable)
+	db.InsertFixtures(sqlbuildertest.Fixtures{"rest_user": {
+		{"id": 1, "name": "user1", "password": "hash1", "deleted_at": ""}, {"id": 2, "name": "user2", "password": "hash2", "deleted_at": ""}, {"id": 3, "name": "user3", "password": "hash3", "deleted_at": ""},
+	}})
+	table := db.Table("rest_user")
+	rest := sqlbuilder.NewRestHandler(table, sqlbuilder.Fields{NewIterateUserId(0), NewRestUserName(""), NewNullUserNickname("")}).WithDefaultSort("-id")
+	mux := http.NewServeMux()
+	mux.Handle("/users/", http.StripPrefix("/users", rest))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func restDo(t *testing.T, method string, url string, body string) (status int, data map[string]any) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data = make(map[string]any)
+	if resp.StatusCode != http.StatusNoContent {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&data))
+	}
+	return resp.StatusCode, data
+}
+
+func TestRestHandler(t *testing.T) {
+	server := newRestServer(t)
+	url := server.URL + "/users/"
+
+	t.Run("get", func(t *testing.T) {
+		status, data := restDo(t, http.MethodGet, url+"2", "")
+		require.Equal(t, http.StatusOK, status)
+		require.EqualValues(t, 2, data["id"])
+		require.Equal(t, "user2", data["name"])
+		require.NotContains(t, data, "password") // 非白名单列不返回
+		require.NotContains(t, data, "deletedAt")
+
+		status, data = restDo(t, http.MethodGet, url+"9", "")
+		require.Equal(t, http.StatusNotFound, status)
+		require.Equal(t, "not_found", data["error"].(map[string]any)["code"])
+
+		status, _ = restDo(t, http.MethodGet, url+"abc", "")
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		status, data := restDo(t, http.MethodGet, url+"?pageSize=2", "")
+		require.Equal(t, http.StatusOK, status)
+		require.EqualValues(t, 3, data["total"])
+		items := data["items"].([]any)
+		require.Len(t, items, 2)
+		require.EqualValues(t, 3, items[0].(map[string]any)["id"])
+		require.NotContains(t, items[0], "password")
+
+		status, data = restDo(t, http.MethodGet, url+"?filter=id:in:1,2&sort=name&fields=name", "")
+		require.Equal(t, http.StatusOK, status)
+		require.EqualValues(t, 2, data["total"])
+		require.Equal(t, []any{map[string]any{"id": float64(1), "name": "user1"}, map[string]any{"id": float64(2), "name": "user2"}}, data["items"])
+
+		for _, query := range []string{"?filter=password:eq:1", "?sort=password", "?fields=password", "?fields=deleted_at", "?pageIndex=x"} {
+			status, data = restDo(t, http.MethodGet, url+query, "")
+			require.Equal(t, http.StatusBadRequest, status, query)
+			require.Equal(t, "invalid_argument", data["error"].(map[string]any)["code"], query)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		status, data := restDo(t, http.MethodPost, url, `{"name":"user4"}`)
+		require.Equal(t, http.StatusCreated, status)
+		require.EqualValues(t, 4, data["id"])
+		require.Equal(t, "user4", data["name"])
+
+		status, _ = restDo(t, http.MethodPost, url, `{}`)
+		require.Equal(t, http.StatusBadRequest, status)
+		for _, body := range []string{`{"name":"user5","role":"admin"}`, `{"id":5.5,"name":"user5"}`} {
+			status, _ = restDo(t, http.MethodPost, url, body)
+			require.Equal(t, http.StatusBadRequest, status, body)
+		}
+		for _, body := range []string{`{"name":{"a":1}}`, `{"name":["user5"]}`} { // 不能静默转换为空字符串
+			status, data = restDo(t, http.MethodPost, url, body)
+			require.Equal(t, http.StatusBadRequest, status, body)
+			require.Contains(t, data["error"].(map[string]any)["message"], "required scalar", body)
+		}
+		status, data = restDo(t, http.MethodPost, url, `{"name":"user1"}`)
+		require.Equal(t, http.StatusConflict, status)
+		require.Equal(t, "conflict", data["error"].(map[string]any)["code"])
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		status, data := restDo(t, http.MethodPatch, url+"1", `{"name":"user1x"}`)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "user1x", data["name"])
+
+		status, data = restDo(t, http.MethodPatch, url+"1", `{"nickname":"nick1"}`)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "nick1", data["nickname"])
+		status, data = restDo(t, http.MethodPatch, url+"1", `{"nickname":null}`)
+		require.Equal(t, http.StatusOK, status)
+		require.Contains(t, data, "nickname")
+		require.Nil(t, data["nickname"])
+		require.Equal(t, "user1x", data["name"]) // 未出现的键不更新
+
+		status, data = restDo(t, http.MethodPatch, url+"1", `{"name":null}`)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+		require.Equal(t, "constraint_violation", data["error"].(map[string]any)["code"])
+		for _, body := range []string{`{"id":5}`, `{"name":{"a":1}}`, `[1]`, `{"role":"admin"}`} {
+			status, _ = restDo(t, http.MethodPatch, url+"1", body)
+			require.Equal(t, http.StatusBadRequest, status, body)
+		}
+		status, _ = restDo(t, http.MethodPatch, url+"9", `{"name":"x"}`)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		status, _ := restDo(t, http.MethodDelete, url+"3", "")
+		require.Equal(t, http.StatusNoContent, status)
+		status, _ = restDo(t, http.MethodGet, url+"3", "")
+		require.Equal(t, http.StatusNotFound, status)
+		status, _ = restDo(t, http.MethodDelete, url+"3", "")
+		require.Equal(t, http.StatusNotFound, status)
+		status, _ = restDo(t, http.MethodPatch, url+"3", `{"name":"x"}`)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestRestHandlerWithoutDeletedAt(t *testing.T) {
+	table := sqlbuilder.NewTableConfig("rest_tag").AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewRestUserName)),
+	).AddIndexs(fkPrimaryIndex)
+	db := sqlbuildertest.New(t, table)
+	db.InsertFixtures(sqlbuildertest.Fixtures{"rest_tag": {{"id": 1, "name": "tag1"}}})
+	rest := sqlbuilder.NewRestHandler(db.Table("rest_tag"), sqlbuilder.Fields{NewIterateUserId(0), NewRestUserName("")})
+	server := httptest.NewServer(http.StripPrefix("/tags", rest))
+	t.Cleanup(server.Close)
+
+	status, data := restDo(t, http.MethodDelete, server.URL+"/tags/1", "")
+	require.Equal(t, http.StatusMethodNotAllowed, status) // 不支持物理删除
+	require.Equal(t, "method_not_allowed", data["error"].(map[string]any)["code"])
+	status, data = restDo(t, http.MethodGet, server.URL+"/tags/1", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "tag1", data["name"])
+}
@@ -2,6 +2,7 @@ package sqlbuilder
 
 import (
 	"context"
+	"slices"
 
 	"github.com/pkg/errors"
 )
@@ -17,6 +18,18 @@ func (ctx ModelMiddlewareContext) append(fns ...ModelMiddleware) ModelMiddleware
 	ctx.middlewares = ctx.middlewares.append(fns...)
 	return ctx
 }
+
+// remove 移除指定名称的中间件,用于转交给其它构造器执行时避免重复执行自身
+func (ctx ModelMiddlewareContext) remove(names ...string) ModelMiddlewareContext {
+	middlewares := make(ModelMiddlewares, 0, len(ctx.middlewares))
+	for _, middleware := range ctx.middlewares {
+		if !slices.Contains(names, middleware.Name) {
+			middlewares = append(middlewares, middleware)
+		}
+	}
+	ctx.middlewares = middlewares
+	return ctx
+}
 func (ctx ModelMiddlewareContext) GetRepository(tableIdentity string) (repository Repository) {
 	table, err := ctx.tableConfigs.GetByIdentity(tableIdentity)
 	if err != nil {
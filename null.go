@@ -0,0 +1,114 @@
+package sqlbuilder
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// NullValue 显式 SQL NULL 值类型,使用 Null
+type NullValue struct{}
+
+// Null 显式写入 SQL NULL,区别于 nil(nil 代表忽略该字段):Field.SetValue(Null) 后 insert、update、set 写入 NULL,where 生成 IS NULL;
+// 字段 Schema.Required 或列 ColumnConfig.NotNull 时写入、校验返回 ErrNullNotAllowed
+var Null = NullValue{}
+
+// ErrNullNotAllowed 不可为空的字段写入 Null
+var ErrNullNotAllowed = errors.New("null not allowed")
+
+const Field_name_mergePatch = "_mergePatch"
+
+// ErrInvalidMergePatch merge-patch 文档格式错误或字段不在白名单
+var ErrInvalidMergePatch = errors.New("invalid merge patch")
+
+func IsNullValue(val any) bool {
+	if IsNil(val) {
+		return false
+	}
+	_, ok := reflect.Indirect(reflect.ValueOf(val)).Interface().(NullValue)
+	return ok
+}
+
+// validateNull 值为 Null 时校验字段是否可为空
+func (f Field) validateNull() (err error) {
+	if !IsNullValue(f.value) {
+		return nil
+	}
+	if f.Schema != nil && f.Schema.Required {
+		err = errors.WithMessagef(ErrNullNotAllowed, "field:%s is required", f.Name)
+		return err
+	}
+	if col, ok := f.table.Columns.GetByFieldName(f.Name); ok && col.NotNull {
+		err = errors.WithMessagef(ErrNullNotAllowed, "column:%s.%s not null", f.table.Name, col.DbName)
+		return err
+	}
+	return nil
+}
+
+// MergePatchFields 按 RFC 7396 JSON merge-patch 语义将 JSON 对象转换为更新字段:未出现的键不更新,显式 null 写入 SQL NULL,
+// 其它值按白名单字段(按 Field.Name 匹配)Schema 类型转换,数组整体替换(序列化为 JSON 字符串);
+// 列值不做深度合并,成员值为对象时返回 ErrInvalidMergePatch
+func MergePatchFields(doc []byte, whitelist Fields) (fs Fields, err error) {
+	patch := make(map[string]any)
+	err = json.Unmarshal(doc, &patch)
+	if err == nil && patch == nil {
+		err = errors.New("null document")
+	}
+	if err != nil {
+		err = errors.WithMessagef(ErrInvalidMergePatch, "merge patch must be json object:%s", err.Error())
+		return nil, err
+	}
+	names := make([]string, 0, len(patch))
+	for name := range patch {
+		names = append(names, name)
+	}
+	sort.Strings(names) // 固定字段顺序,生成 SQL 稳定
+	fs = make(Fields, 0, len(names))
+	for _, name := range names {
+		allowed, ok := whitelist.GetByName(name)
+		if !ok {
+			err = errors.WithMessagef(ErrInvalidMergePatch, "field:%s not allowed", name)
+			return nil, err
+		}
+		f, err := mergePatchField(allowed, patch[name])
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+// mergePatchField merge-patch 成员值转换为字段,null 转换为 Null
+func mergePatchField(allowed *Field, v any) (f *Field, err error) {
+	switch val := v.(type) {
+	case nil:
+		return allowed.Copy().SetValue(Null), nil
+	case map[string]any:
+		err = errors.WithMessagef(ErrInvalidMergePatch, "field:%s nested object merge not supported", allowed.Name)
+		return nil, err
+	case []any:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		v = string(b)
+	}
+	value, err := convertBySchemaType(allowed, v)
+	if err != nil {
+		err = errors.WithMessage(ErrInvalidMergePatch, err.Error())
+		return nil, err
+	}
+	return allowed.Copy().SetValue(value), nil
+}
+
+// WithMergePatch 按 JSON merge-patch 文档追加更新字段,见 MergePatchFields,文档非法时执行返回 ErrInvalidMergePatch
+func (p *UpdateParam) WithMergePatch(doc []byte, whitelist Fields) *UpdateParam {
+	fs, err := MergePatchFields(doc, whitelist)
+	if err != nil {
+		return p.AppendFields(newErrorField(Field_name_mergePatch, err))
+	}
+	return p.AppendFields(fs...)
+}
@@ -0,0 +1,74 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+var nullUserTable = sqlbuilder.NewTableConfig("null_user").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)).WithNullable(false),
+	sqlbuilder.NewColumn("nickname", sqlbuilder.GetField(NewNullUserNickname)),
+).AddIndexs(fkPrimaryIndex)
+
+func newNullTable(t *testing.T) sqlbuilder.TableConfig {
+	db := sqlbuildertest.New(t, nullUserTable)
+	db.InsertFixtures(sqlbuildertest.Fixtures{"null_user": {{"id": 1, "name": "user1", "nickname": "nick1"}}})
+	return db.Table("null_user")
+}
+
+func TestNull(t *testing.T) {
+	table := newNullTable(t)
+	whereId := func(id int) *sqlbuilder.Field {
+		return NewIterateUserId(id).AppendWhereFn(sqlbuilder.ValueFnForward).ShieldUpdate(true)
+	}
+
+	t.Run("insert update where", func(t *testing.T) {
+		sql, err := sqlbuilder.NewInsertBuilder(table).ToSQL(sqlbuilder.Fields{NewIterateUserName("user2"), NewNullUserNickname("").SetValue(sqlbuilder.Null)})
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO `null_user` (`name`, `nickname`) VALUES ('user2', NULL)", sql)
+
+		sql, err = sqlbuilder.NewUpdateBuilder(table).ToSQL(sqlbuilder.Fields{NewNullUserNickname("").SetValue(sqlbuilder.Null), whereId(1)})
+		require.NoError(t, err)
+		require.Equal(t, "UPDATE `null_user` SET `nickname`=NULL WHERE (`null_user`.`id` = 1)", sql)
+
+		sql, err = sqlbuilder.NewListBuilder(table).ToSQL(sqlbuilder.Fields{NewNullUserNickname("").SetValue(sqlbuilder.Null).AppendWhereFn(sqlbuilder.ValueFnForward)})
+		require.NoError(t, err)
+		require.Contains(t, sql, "WHERE (`null_user`.`nickname` IS NULL)")
+	})
+
+	t.Run("not nullable", func(t *testing.T) {
+		_, err := sqlbuilder.NewUpdateBuilder(table).ToSQL(sqlbuilder.Fields{NewIterateUserName("").SetValue(sqlbuilder.Null), whereId(1)})
+		require.ErrorIs(t, err, sqlbuilder.ErrNullNotAllowed)
+
+		required := NewNullUserNickname("").SetValue(sqlbuilder.Null).MergeSchema(sqlbuilder.Schema{Required: true})
+		err = sqlbuilder.Fields{required}.Validate()
+		require.ErrorIs(t, err, sqlbuilder.ErrNullNotAllowed)
+	})
+
+	t.Run("merge patch", func(t *testing.T) {
+		whitelist := sqlbuilder.Fields{NewIterateUserName(""), NewNullUserNickname("")}
+		fs, err := sqlbuilder.MergePatchFields([]byte(`{"nickname":null,"name":"user1x"}`), whitelist)
+		require.NoError(t, err)
+		require.Len(t, fs, 2)
+
+		err = sqlbuilder.NewUpdateBuilder(table).WithMergePatch([]byte(`{"nickname":null}`), whitelist).AppendFields(whereId(1)).Exec()
+		require.NoError(t, err)
+		row := make(map[string]any)
+		exists, err := sqlbuilder.NewFirstBuilder(table).AppendFields(whereId(1)).First(&row)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, "user1", row["name"]) // 未出现的键不更新
+		require.Nil(t, row["nickname"])
+
+		for _, doc := range []string{`null`, `[1]`, `{"password":"x"}`, `{"nickname":{"a":1}}`} {
+			_, err = sqlbuilder.MergePatchFields([]byte(doc), whitelist)
+			require.ErrorIs(t, err, sqlbuilder.ErrInvalidMergePatch, doc)
+		}
+		err = sqlbuilder.NewUpdateBuilder(table).WithMergePatch([]byte(`{"password":"x"}`), whitelist).AppendFields(whereId(1)).Exec()
+		require.ErrorIs(t, err, sqlbuilder.ErrInvalidMergePatch)
+	})
+}
@@ -0,0 +1,99 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidProjection 查询列不在表列配置中
+var ErrInvalidProjection = errors.New("invalid projection")
+
+// projection 客户端指定查询列(如 ?fields=id,name,status),按 ColumnConfig.FieldName、DbName 匹配(不区分大小写)
+type projection []string
+
+// columns 生成最小查询列:指定列、主键列、预加载关系本表关联列(关系依赖这些列匹配关联数据),去重保持顺序
+func (names projection) columns(table TableConfig, preloads []string) (columns []any, err error) {
+	dbNames := make([]string, 0, len(names))
+	add := func(dbName string) {
+		if !slices.Contains(dbNames, dbName) {
+			dbNames = append(dbNames, dbName)
+		}
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		col, ok := table.Columns.GetByFieldName(name)
+		if !ok {
+			col, ok = table.Columns.GetByDbName(name)
+		}
+		if !ok {
+			err = errors.WithMessagef(ErrInvalidProjection, "field:%s not found in table:%s", name, table.Name)
+			return nil, err
+		}
+		add(col.DbName)
+	}
+	if len(dbNames) == 0 {
+		return nil, nil
+	}
+	if primary, exists := table.Indexs.GetPrimary(); exists {
+		for _, columnName := range primary.ColumnNames(table) {
+			add(columnName)
+		}
+	}
+	for _, preload := range parsePreloadNames(preloads) {
+		relation, ok := table.Relations.GetByName(preload.name)
+		if !ok {
+			continue // 预加载时报错
+		}
+		if relation.Local.Table.Name != "" && relation.Local.Table.Name != table.Name {
+			continue
+		}
+		col, err := table.Columns.GetByFieldNameAsError(relation.Local.Field.Name)
+		if err != nil {
+			return nil, err
+		}
+		add(col.DbName)
+	}
+	columns = make([]any, 0, len(dbNames))
+	for _, dbName := range dbNames {
+		columns = append(columns, goqu.I(fmt.Sprintf("%s.%s", table.BaseName(), dbName)))
+	}
+	return columns, nil
+}
+
+// WithProjection 只查询指定列(按 ColumnConfig.FieldName 或 DbName 匹配),自动包含主键及预加载关系所需列,未在表列配置中时查询返回 ErrInvalidProjection;
+// 优先级高于字段 SelectColumns 及结果结构体 FieldsI 隐式查询列
+func (p *ListParam) WithProjection(names ...string) *ListParam {
+	p.projection = append(p.projection, names...)
+	return p
+}
+
+// WithProjection 同 ListParam.WithProjection
+func (p *FirstParam) WithProjection(names ...string) *FirstParam {
+	p.projection = append(p.projection, names...)
+	return p
+}
+
+// WithProjection 同 ListParam.WithProjection,分表分页各分表查询相同列
+func (p *PaginationParam) WithProjection(names ...string) *PaginationParam {
+	p.projection = append(p.projection, names...)
+	return p
+}
+
+// getProjectionSelectColumns 指定查询列时返回查询列,否则返回默认查询列
+func (p *SQLParam[T]) getProjectionSelectColumns(table TableConfig, fs Fields, names projection, preloads []string) (selectColumns []any, err error) {
+	selectColumns, err = names.columns(table, preloads)
+	if err != nil {
+		return nil, err
+	}
+	if len(selectColumns) > 0 {
+		return selectColumns, nil
+	}
+	return p.getSelectColumns(table, fs), nil
+}
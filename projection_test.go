@@ -0,0 +1,50 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+)
+
+func TestProjection(t *testing.T) {
+	_, item, _ := newRelationTables(t)
+
+	t.Run("list", func(t *testing.T) {
+		builder := sqlbuilder.NewListBuilder(item).WithProjection("name")
+		sql, err := builder.ToSQL(builder.Fields())
+		require.NoError(t, err)
+		require.Contains(t, sql, "SELECT `rel_order_item`.`name`, `rel_order_item`.`id` FROM `rel_order_item`")
+
+		rows := make([]map[string]any, 0)
+		err = sqlbuilder.NewListBuilder(item).WithProjection("name").List(&rows)
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		require.Equal(t, map[string]any{"id": int64(1), "name": "a"}, rows[0])
+	})
+
+	t.Run("preload keys", func(t *testing.T) {
+		items := make([]relOrderItem, 0)
+		err := sqlbuilder.NewListBuilder(item).WithProjection("id").Preload("order").List(&items)
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		require.Equal(t, "", items[0].Name) // 未查询列保持零值
+		require.Equal(t, 1, items[0].OrderId)
+		require.NotNil(t, items[0].Order)
+		require.Equal(t, "o1", items[0].Order.Name)
+	})
+
+	t.Run("first", func(t *testing.T) {
+		var row relOrderItem
+		exists, err := sqlbuilder.NewFirstBuilder(item).WithProjection("order_id").AppendFields(NewIterateUserId(3).AppendWhereFn(sqlbuilder.ValueFnForward)).First(&row)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, relOrderItem{Id: 3, OrderId: 2}, row)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rows := make([]map[string]any, 0)
+		err := sqlbuilder.NewListBuilder(item).WithProjection("id", "password").List(&rows)
+		require.ErrorIs(t, err, sqlbuilder.ErrInvalidProjection)
+	})
+}
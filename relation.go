@@ -0,0 +1,275 @@
+package sqlbuilder
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// RelationType 表关系类型
+type RelationType string
+
+const (
+	RelationType_hasOne    RelationType = "hasOne"    // 一对一,关联表持有本表键,如 用户→用户资料
+	RelationType_hasMany   RelationType = "hasMany"   // 一对多,关联表持有本表键,如 订单→订单明细
+	RelationType_belongsTo RelationType = "belongsTo" // 从属,本表持有关联表键,如 订单明细→订单
+)
+
+// Relation 表关系,Local、Foreign 和 NewOn 的 OnUnit 一致:
+// Local.Field 为本表关联字段(Local.Table 可为空,默认为主查询表),Foreign.Table、Foreign.Field 为关联表及关联字段,Foreign.WhereFields 为关联查询附加条件
+type Relation struct {
+	Name    string // 预加载名称,同时是结果结构体中接收关联数据的字段名(不区分大小写)或结果map的键
+	Type    RelationType
+	Local   OnUnit
+	Foreign OnUnit
+}
+
+func (r Relation) isMany() bool {
+	return r.Type == RelationType_hasMany
+}
+
+type Relations []Relation
+
+func (rs Relations) GetByName(name string) (relation *Relation, exists bool) {
+	for _, r := range rs {
+		if strings.EqualFold(r.Name, name) {
+			return &r, true
+		}
+	}
+	return nil, false
+}
+
+func (t TableConfig) AddRelations(relations ...Relation) TableConfig {
+	rs := make(Relations, 0, len(t.Relations)+len(relations))
+	rs = append(rs, t.Relations...)
+	t.Relations = append(rs, relations...) // 复制,不影响原表配置
+	return t
+}
+
+// preloadName 预加载名称,支持 "items.product" 格式嵌套加载
+type preloadName struct {
+	name   string
+	nested []string
+}
+
+func parsePreloadNames(names []string) (preloadNames []preloadName) {
+	for _, name := range names {
+		first, rest, _ := strings.Cut(name, ".")
+		i := -1
+		for j := range preloadNames {
+			if strings.EqualFold(preloadNames[j].name, first) {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			preloadNames = append(preloadNames, preloadName{name: first})
+			i = len(preloadNames) - 1
+		}
+		if rest != "" {
+			preloadNames[i].nested = append(preloadNames[i].nested, rest)
+		}
+	}
+	return preloadNames
+}
+
+// preloadRelations 主查询结果(结构体、结构体切片、map、map切片)加载关联数据,每个关系一次 IN 查询;关联查询使用主查询句柄,保证事务内一致
+func preloadRelations(ctx context.Context, table TableConfig, handler Handler, result any, names []string) (err error) {
+	if len(names) == 0 {
+		return nil
+	}
+	rows := relationRows(reflect.ValueOf(result))
+	if len(rows) == 0 {
+		return nil
+	}
+	for _, preload := range parsePreloadNames(names) {
+		relation, ok := table.Relations.GetByName(preload.name)
+		if !ok {
+			err = errors.Errorf("relation:%s not found in table:%s", preload.name, table.Name)
+			return err
+		}
+		err = relation.load(ctx, table, handler, rows, preload.nested)
+		if err != nil {
+			err = errors.WithMessagef(err, "preload relation:%s", preload.name)
+			return err
+		}
+	}
+	return nil
+}
+
+// relationRows 结果行,结构体行可寻址以便赋值
+func relationRows(rv reflect.Value) (rows []reflect.Value) {
+	rv = indirectValue(rv)
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map:
+		return []reflect.Value{rv}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			row := indirectValue(rv.Index(i))
+			if row.IsValid() {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
+func (r Relation) load(ctx context.Context, table TableConfig, handler Handler, rows []reflect.Value, nested []string) (err error) {
+	localTable := r.Local.Table
+	if localTable.Name == "" {
+		localTable = table
+	}
+	localCol, err := localTable.Columns.GetByFieldNameAsError(r.Local.Field.Name)
+	if err != nil {
+		return err
+	}
+	foreignTable := r.Foreign.Table
+	foreignCol, err := foreignTable.Columns.GetByFieldNameAsError(r.Foreign.Field.Name)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]any, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		val := relationValue(row, localCol)
+		if IsNil(val) {
+			continue
+		}
+		key := cast.ToString(val)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, val)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	elemType, err := r.elemType(rows[0])
+	if err != nil {
+		return err
+	}
+	childrenRef := reflect.New(reflect.SliceOf(elemType))
+	inField := foreignCol.MakeField(keys).AppendWhereFn(ValueFnForward)
+	err = NewListBuilder(foreignTable.WithHandler(handler)).WithContext(ctx).AppendFields(inField).AppendFields(r.Foreign.WhereFields...).List(childrenRef.Interface())
+	if err != nil {
+		return err
+	}
+	err = preloadRelations(ctx, foreignTable, handler, childrenRef.Interface(), nested)
+	if err != nil {
+		return err
+	}
+
+	children := childrenRef.Elem()
+	groups := make(map[string][]reflect.Value)
+	for i := 0; i < children.Len(); i++ {
+		child := children.Index(i)
+		key := cast.ToString(relationValue(child, foreignCol))
+		groups[key] = append(groups[key], child)
+	}
+	for _, row := range rows {
+		val := relationValue(row, localCol)
+		if IsNil(val) {
+			continue
+		}
+		err = r.assign(row, groups[cast.ToString(val)])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// elemType 关联数据元素类型:结构体字段为 []T、[]*T、T、*T 时为 T,map 行为 map[string]any
+func (r Relation) elemType(row reflect.Value) (elemType reflect.Type, err error) {
+	if row.Kind() == reflect.Map {
+		return reflect.TypeFor[map[string]any](), nil
+	}
+	field, err := r.destField(row)
+	if err != nil {
+		return nil, err
+	}
+	elemType = field.Type()
+	if elemType.Kind() == reflect.Slice {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	return elemType, nil
+}
+
+func (r Relation) destField(row reflect.Value) (field reflect.Value, err error) {
+	field = row.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, r.Name) })
+	if !field.IsValid() || !field.CanSet() {
+		err = errors.Errorf("relation:%s not found settable field in %s", r.Name, row.Type().String())
+		return field, err
+	}
+	if r.isMany() && field.Kind() != reflect.Slice {
+		err = errors.Errorf("relation:%s is %s,field in %s must be slice", r.Name, r.Type, row.Type().String())
+		return field, err
+	}
+	return field, nil
+}
+
+func (r Relation) assign(row reflect.Value, children []reflect.Value) (err error) {
+	if row.Kind() == reflect.Map {
+		var val any
+		if r.isMany() {
+			list := make([]map[string]any, 0, len(children))
+			for _, child := range children {
+				list = append(list, child.Interface().(map[string]any))
+			}
+			val = list
+		} else if len(children) > 0 {
+			val = children[0].Interface()
+		}
+		row.SetMapIndex(reflect.ValueOf(r.Name), reflect.ValueOf(&val).Elem())
+		return nil
+	}
+	field, err := r.destField(row)
+	if err != nil {
+		return err
+	}
+	if field.Kind() == reflect.Slice {
+		list := reflect.MakeSlice(field.Type(), 0, len(children))
+		for _, child := range children {
+			list = reflect.Append(list, relationElem(child, field.Type().Elem()))
+		}
+		field.Set(list)
+		return nil
+	}
+	if len(children) > 0 {
+		field.Set(relationElem(children[0], field.Type()))
+	}
+	return nil
+}
+
+func relationElem(child reflect.Value, typ reflect.Type) reflect.Value {
+	if typ.Kind() != reflect.Pointer {
+		return child
+	}
+	ptr := reflect.New(child.Type())
+	ptr.Elem().Set(child)
+	return ptr
+}
+
+// relationValue 读取结果行关联列值:实现 FieldsI 的结构体按字段名读取,其它按列名、字段名读取
+func relationValue(row reflect.Value, col ColumnConfig) any {
+	row = indirectValue(row)
+	if row.Kind() == reflect.Struct && row.CanAddr() {
+		if fi, ok, _ := IsStructImplementFieldsI(row); ok {
+			if f, ok := fi.Fields().GetByName(col.FieldName); ok {
+				if ref, err := f.GetValueRef(); err == nil {
+					return valueInterface(reflect.ValueOf(ref))
+				}
+			}
+		}
+	}
+	return shardedOrderKey{columnName: col.DbName, fieldName: col.FieldName}.value(row)
+}
@@ -0,0 +1,165 @@
+package sqlbuilder_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+type relOrder struct {
+	Id    int            `db:"id"`
+	Name  string         `db:"name"`
+	Items []relOrderItem `db:"-"`
+}
+
+type relOrderItem struct {
+	Id      int       `db:"id"`
+	OrderId int       `db:"order_id"`
+	Name    string    `db:"name"`
+	Order   *relOrder `db:"-"`
+}
+
+// relOrderItemTagged db 标签为字段名 orderId,与列名 order_id 不同
+type relOrderItemTagged struct {
+	Id      int       `db:"id"`
+	OrderId int       `db:"orderId"`
+	Order   *relOrder `db:"-"`
+}
+
+type relOrderItemModel struct {
+	Id      int
+	OrderId int
+	Name    string
+}
+
+func (m *relOrderItemModel) Fields() sqlbuilder.Fields {
+	return sqlbuilder.Fields{
+		NewIterateUserId(0).SetRefValue(&m.Id),
+		NewRelOrderId(0).SetRefValue(&m.OrderId),
+		NewIterateUserName("").SetRefValue(&m.Name),
+	}
+}
+
+type relOrderModel struct {
+	Id    int                 `db:"id"`
+	Items []relOrderItemModel `db:"-"`
+}
+
+// queryCountHandler 统计查询次数
+type queryCountHandler struct {
+	sqlbuilder.Handler
+	queries *int
+}
+
+func (h queryCountHandler) Query(ctx context.Context, sql string, result any) (err error) {
+	*h.queries++
+	return h.Handler.Query(ctx, sql, result)
+}
+
+var relOrderTable = sqlbuilder.NewTableConfig("rel_order").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+).AddIndexs(fkPrimaryIndex)
+
+var relOrderItemTable = sqlbuilder.NewTableConfig("rel_order_item").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("order_id", sqlbuilder.GetField(NewRelOrderId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+).AddIndexs(fkPrimaryIndex)
+
+func newRelationTables(t *testing.T) (order sqlbuilder.TableConfig, item sqlbuilder.TableConfig, queries *int) {
+	db := sqlbuildertest.New(t, relOrderTable, relOrderItemTable)
+	db.InsertFixtures(sqlbuildertest.Fixtures{
+		"rel_order":      {{"id": 1, "name": "o1"}, {"id": 2, "name": "o2"}, {"id": 3, "name": "o3"}},
+		"rel_order_item": {{"id": 1, "order_id": 1, "name": "a"}, {"id": 2, "order_id": 1, "name": "b"}, {"id": 3, "order_id": 2, "name": "c"}},
+	})
+	queries = new(int)
+	handler := queryCountHandler{Handler: db.Handler(), queries: queries}
+	order = relOrderTable.WithHandler(handler)
+	item = relOrderItemTable.WithHandler(handler)
+	item = item.AddRelations(sqlbuilder.Relation{
+		Name:    "order",
+		Type:    sqlbuilder.RelationType_belongsTo,
+		Local:   sqlbuilder.OnUnit{Field: NewRelOrderId(0)},
+		Foreign: sqlbuilder.OnUnit{Table: order, Field: NewIterateUserId(0)},
+	})
+	order = order.AddRelations(sqlbuilder.Relation{
+		Name:    "items",
+		Type:    sqlbuilder.RelationType_hasMany,
+		Local:   sqlbuilder.OnUnit{Field: NewIterateUserId(0)},
+		Foreign: sqlbuilder.OnUnit{Table: item, Field: NewRelOrderId(0)},
+	})
+	return order, item, queries
+}
+
+func TestPreload(t *testing.T) {
+	t.Run("has many", func(t *testing.T) {
+		order, _, queries := newRelationTables(t)
+		orders := make([]relOrder, 0)
+		err := sqlbuilder.NewListBuilder(order).Preload("items.order").List(&orders)
+		require.NoError(t, err)
+		*queries = 0 // 首次查询包含建表检测查询,再次查询统计
+		orders = make([]relOrder, 0)
+		err = sqlbuilder.NewListBuilder(order).Preload("items.order").List(&orders)
+		require.NoError(t, err)
+		require.Equal(t, 3, *queries) // 主查询、items、items.order 各一次
+		require.Len(t, orders, 3)
+		require.Equal(t, []string{"a", "b"}, []string{orders[0].Items[0].Name, orders[0].Items[1].Name})
+		require.Equal(t, "o1", orders[0].Items[1].Order.Name)
+		require.Len(t, orders[1].Items, 1)
+		require.Empty(t, orders[2].Items)
+	})
+
+	t.Run("belongs to", func(t *testing.T) {
+		_, item, _ := newRelationTables(t)
+		orderItem := relOrderItem{}
+		exists, err := sqlbuilder.NewFirstBuilder(item).AppendFields(NewIterateUserId(3).AppendWhereFn(sqlbuilder.ValueFnForward)).Preload("order").First(&orderItem)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.NotNil(t, orderItem.Order)
+		require.Equal(t, "o2", orderItem.Order.Name)
+	})
+
+	t.Run("fieldsI", func(t *testing.T) {
+		order, _, _ := newRelationTables(t)
+		orders := make([]relOrderModel, 0)
+		err := sqlbuilder.NewListBuilder(order).Preload("items").List(&orders)
+		require.NoError(t, err)
+		require.Len(t, orders[0].Items, 2)
+		require.Equal(t, relOrderItemModel{Id: 3, OrderId: 2, Name: "c"}, orders[1].Items[0])
+	})
+
+	t.Run("struct tag differs from column", func(t *testing.T) {
+		_, item, _ := newRelationTables(t)
+		items := make([]relOrderItemTagged, 0)
+		id := NewIterateUserId(0).SetSelectColumns("id", goqu.I("order_id").As("orderId"))
+		err := sqlbuilder.NewListBuilder(item).AppendFields(id).Preload("order").List(&items)
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		orderNames := make([]string, 0)
+		for _, orderItem := range items {
+			require.NotNil(t, orderItem.Order)
+			orderNames = append(orderNames, orderItem.Order.Name)
+		}
+		require.Equal(t, []string{"o1", "o1", "o2"}, orderNames)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		order, _, _ := newRelationTables(t)
+		orders := make([]map[string]any, 0)
+		err := sqlbuilder.NewListBuilder(order).Preload("items").List(&orders)
+		require.NoError(t, err)
+		require.Len(t, orders[0]["items"], 2)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		order, _, _ := newRelationTables(t)
+		orders := make([]relOrder, 0)
+		err := sqlbuilder.NewListBuilder(order).Preload("customer").List(&orders)
+		require.Error(t, err)
+	})
+}
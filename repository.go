@@ -1,6 +1,9 @@
 package sqlbuilder
 
-import "context"
+import (
+	"context"
+	"database/sql"
+)
 
 type SelectBuilderFnsI interface {
 	SelectBuilderFn() (selectBuilder SelectBuilderFns)
@@ -136,6 +139,7 @@ type Repository struct {
 	tableConfig TableConfig
 	RepositoryCommand
 	RepositoryQuery
+	txRetryPolicy *TxRetryPolicy // 事务重试策略,为空时不重试
 }
 
 func NewRepository(tableConfig TableConfig) Repository {
@@ -156,7 +160,8 @@ func (s Repository) WithModelMiddleware(modelMiddlewares ...ModelMiddleware) Rep
 	return s
 }
 
-func (r Repository) Transaction(fc func(txRepository Repository) (err error)) (err error) {
+// Transaction 开启事务,opts 可设置隔离级别、只读;在事务仓库中再次调用时使用保存点嵌套,内层失败只回滚内层
+func (r Repository) Transaction(fc func(txRepository Repository) (err error), opts ...*sql.TxOptions) (err error) {
 
 	err = r.TransactionForMutiTable(func(tx Handler) error {
 		tableConfig := r.tableConfig.WithHandler(tx)
@@ -170,15 +175,25 @@ func (r Repository) Transaction(fc func(txRepository Repository) (err error)) (e
 			return err
 		}
 		return nil
-	})
+	}, opts...)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r Repository) TransactionForMutiTable(fc func(tx Handler) (err error)) (err error) {
-	err = r.GetTable().GetHandlerWithInitTable().Transaction(fc)
+// WithTxRetryPolicy 设置事务重试策略,死锁、锁等待超时等可重试错误时重新执行整个事务函数
+func (r Repository) WithTxRetryPolicy(policy TxRetryPolicy) Repository {
+	r.txRetryPolicy = &policy
+	return r
+}
+
+func (r Repository) TransactionForMutiTable(fc func(tx Handler) (err error), opts ...*sql.TxOptions) (err error) {
+	handler := r.GetTable().GetHandlerWithInitTable()
+	if r.txRetryPolicy != nil {
+		return r.txRetryPolicy.Transaction(handler, fc, opts...)
+	}
+	err = handler.Transaction(fc, opts...)
 	if err != nil {
 		return err
 	}
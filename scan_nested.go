@@ -0,0 +1,415 @@
+package sqlbuilder
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// ErrAmbiguousColumn 结果集中多个列映射到同一结构体字段,或列前缀匹配到多个嵌套结构体
+var ErrAmbiguousColumn = errors.New("ambiguous column")
+
+// ErrNestedScanParentKey 结果列映射到嵌套结构体切片,但未选择父级记录主键列,无法合并父级记录
+var ErrNestedScanParentKey = errors.New("nested scan parent key column not found")
+
+// nestedScanTarget 嵌套结构体指针、嵌套结构体切片,整行对应列均为 NULL 时(如 left join 无匹配)不赋值
+type nestedScanTarget struct {
+	traversal []int // 目标字段在结果结构体中的路径
+	isSlice   bool
+	elemType  reflect.Type // 结构体类型
+	columns   []int
+}
+
+type nestedScanColumn struct {
+	traversal       []int             // 直接扫描路径,为空且 target 为空时丢弃该列
+	target          *nestedScanTarget // 嵌套目标
+	targetTraversal []int             // 在嵌套结构体中的路径
+}
+
+// nestedScanPlan 结果列到结构体字段的映射:
+// 列名依次按 db 标签(含 sqlx 的 a.b 嵌套格式)匹配,未匹配且带表前缀(如 TableConfig 别名、DBColumnName().FullName() 生成的 order.id)时,
+// 前缀按字段 db 标签、字段名、匿名嵌入结构体类型名(忽略大小写及下划线)匹配嵌套结构体、嵌套结构体指针、嵌套结构体切片
+type nestedScanPlan struct {
+	columns    []nestedScanColumn
+	targets    []*nestedScanTarget
+	keyColumns []int // 父级记录主键列,用于合并嵌套切片所在的父级记录
+}
+
+var nestedScanPlanCache sync.Map
+
+type nestedScanPlanKey struct {
+	typ     reflect.Type
+	columns string
+}
+
+func getNestedScanPlan(typ reflect.Type, columns []string) (plan *nestedScanPlan, err error) {
+	key := nestedScanPlanKey{typ: typ, columns: strings.Join(columns, ",")}
+	if v, ok := nestedScanPlanCache.Load(key); ok {
+		return v.(*nestedScanPlan), nil
+	}
+	plan, err = newNestedScanPlan(typ, columns)
+	if err != nil {
+		return nil, err
+	}
+	nestedScanPlanCache.Store(key, plan)
+	return plan, nil
+}
+
+func newNestedScanPlan(typ reflect.Type, columns []string) (plan *nestedScanPlan, err error) {
+	plan = &nestedScanPlan{columns: make([]nestedScanColumn, len(columns))}
+	traversals := structScanMapper.TraversalsByName(typ, columns)
+	used := make(map[string]string, len(columns))
+	for i, column := range columns {
+		col, err := plan.resolvePrefixColumn(typ, column, i) // 优先按前缀匹配,嵌套结构体指针需判空
+		if err != nil {
+			return nil, err
+		}
+		if len(col.traversal) == 0 && col.target == nil {
+			col.traversal = traversals[i]
+		}
+		var path string
+		switch {
+		case len(col.traversal) > 0:
+			path = fmt.Sprint(col.traversal)
+		case col.target != nil:
+			path = fmt.Sprint(col.target.traversal, col.targetTraversal)
+		default:
+			continue
+		}
+		if previous, ok := used[path]; ok {
+			err = errors.WithMessagef(ErrAmbiguousColumn, "columns %s and %s map to the same field of %s,please alias columns with table prefix", previous, column, typ.String())
+			return nil, err
+		}
+		used[path] = column
+		plan.columns[i] = col
+	}
+	plan.keyColumns = plan.parentKeyColumns(typ)
+	return plan, nil
+}
+
+// parentKeyColumns 父级记录主键列:直接扫描到父级(含匿名嵌入结构体)字段的列中,db 标签带 pk 选项的列,未标注时取 db 标签为 id 的列
+func (plan *nestedScanPlan) parentKeyColumns(typ reflect.Type) (keyColumns []int) {
+	idColumns := make([]int, 0)
+	for i, col := range plan.columns {
+		if len(col.traversal) == 0 {
+			continue
+		}
+		field, ok := parentStructField(typ, col.traversal)
+		if !ok {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("db"), ",")
+		if slices.Contains(strings.Split(options, ","), "pk") {
+			keyColumns = append(keyColumns, i)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, "id") {
+			idColumns = append(idColumns, i)
+		}
+	}
+	if len(keyColumns) > 0 {
+		return keyColumns
+	}
+	return idColumns
+}
+
+// parentStructField 路径仅经过匿名嵌入结构体时返回末端字段,经过嵌套结构体时字段不属于父级记录
+func parentStructField(typ reflect.Type, traversal []int) (field reflect.StructField, ok bool) {
+	for i, index := range traversal {
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		field = typ.Field(index)
+		if i < len(traversal)-1 && !field.Anonymous {
+			return field, false
+		}
+		typ = field.Type
+	}
+	return field, true
+}
+
+func (plan *nestedScanPlan) resolvePrefixColumn(typ reflect.Type, column string, columnIndex int) (col nestedScanColumn, err error) {
+	i := strings.LastIndex(column, ".")
+	if i < 0 {
+		return col, nil
+	}
+	prefix, name := column[:i], column[i+1:]
+	if j := strings.LastIndex(prefix, "."); j > -1 { // schema.table.column
+		prefix = prefix[j+1:]
+	}
+	var matched []reflect.StructField
+	for k := 0; k < typ.NumField(); k++ {
+		field := typ.Field(k)
+		if !field.IsExported() || !matchNestedPrefix(field, prefix) {
+			continue
+		}
+		matched = append(matched, field)
+	}
+	switch len(matched) {
+	case 0:
+		return col, nil
+	case 1:
+	default:
+		err = errors.WithMessagef(ErrAmbiguousColumn, "column %s prefix matches %d fields of %s", column, len(matched), typ.String())
+		return col, err
+	}
+	field := matched[0]
+	fieldType := field.Type
+	isSlice := fieldType.Kind() == reflect.Slice
+	if isSlice {
+		fieldType = fieldType.Elem()
+	}
+	isPtr := fieldType.Kind() == reflect.Pointer
+	if isPtr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() != reflect.Struct || fieldType == reflect.TypeFor[time.Time]() {
+		return col, nil
+	}
+	sub := structScanMapper.TraversalsByName(fieldType, []string{name})[0]
+	if len(sub) == 0 {
+		return col, nil
+	}
+	if !isSlice && !isPtr { // 嵌入或嵌套结构体直接扫描
+		col.traversal = append(append([]int{}, field.Index...), sub...)
+		return col, nil
+	}
+	target := plan.target(field.Index, isSlice, fieldType)
+	target.columns = append(target.columns, columnIndex)
+	col.target = target
+	col.targetTraversal = sub
+	return col, nil
+}
+
+func (plan *nestedScanPlan) target(traversal []int, isSlice bool, elemType reflect.Type) *nestedScanTarget {
+	for _, target := range plan.targets {
+		if slices.Equal(target.traversal, traversal) {
+			return target
+		}
+	}
+	target := &nestedScanTarget{traversal: traversal, isSlice: isSlice, elemType: elemType}
+	plan.targets = append(plan.targets, target)
+	return target
+}
+
+func matchNestedPrefix(field reflect.StructField, prefix string) bool {
+	normalize := func(s string) string { return strings.ToLower(strings.ReplaceAll(s, "_", "")) }
+	prefix = normalize(prefix)
+	tag, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+	if tag != "" && tag != "-" && normalize(tag) == prefix {
+		return true
+	}
+	if normalize(field.Name) == prefix {
+		return true
+	}
+	if field.Anonymous {
+		typ := field.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		return normalize(typ.Name()) == prefix
+	}
+	return false
+}
+
+func (plan *nestedScanPlan) hasSlice() bool {
+	for _, target := range plan.targets {
+		if target.isSlice {
+			return true
+		}
+	}
+	return false
+}
+
+// scanRow 扫描当前行到 dest,嵌套切片追加一个元素
+func (plan *nestedScanPlan) scanRow(rows Rows, dest reflect.Value) (err error) {
+	values := make([]any, len(plan.columns))
+	for i, col := range plan.columns {
+		if len(col.traversal) > 0 {
+			values[i] = reflectx.FieldByIndexes(dest, col.traversal).Addr().Interface()
+			continue
+		}
+		values[i] = new(any)
+	}
+	err = rows.Scan(values...)
+	if err != nil {
+		return err
+	}
+	for _, target := range plan.targets {
+		allNil := true
+		for _, i := range target.columns {
+			if *(values[i].(*any)) != nil {
+				allNil = false
+				break
+			}
+		}
+		if allNil {
+			continue
+		}
+		elem := reflect.New(target.elemType).Elem()
+		for _, i := range target.columns {
+			field := reflectx.FieldByIndexes(elem, plan.columns[i].targetTraversal)
+			err = setScannedValue(field, *(values[i].(*any)))
+			if err != nil {
+				err = errors.WithMessagef(err, "nested scan column index %d", i)
+				return err
+			}
+		}
+		field := reflectx.FieldByIndexes(dest, target.traversal)
+		if !target.isSlice {
+			field.Set(elem.Addr())
+			continue
+		}
+		if field.Type().Elem().Kind() == reflect.Pointer {
+			elem = elem.Addr()
+		}
+		field.Set(reflect.Append(field, elem))
+	}
+	return nil
+}
+
+// parentKey 父级记录主键列的值,用于合并嵌套切片所在的父级记录
+func (plan *nestedScanPlan) parentKey(dest reflect.Value) string {
+	var sb strings.Builder
+	for _, i := range plan.keyColumns {
+		sb.WriteString(fmt.Sprint(reflectx.FieldByIndexes(dest, plan.columns[i].traversal).Interface()))
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// nestedSlicePlan 切片元素为普通结构体(未实现 FieldsI)且结果列映射到嵌套结构体切片时返回扫描计划,否则返回 nil
+func nestedSlicePlan(rows Rows, elemType reflect.Type) (plan *nestedScanPlan, err error) {
+	if elemType.Kind() != reflect.Struct || elemType == reflect.TypeFor[time.Time]() {
+		return nil, nil
+	}
+	if _, ok, _ := IsStructImplementFieldsI(reflect.New(elemType).Elem()); ok {
+		return nil, nil
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	plan, err = getNestedScanPlan(elemType, columns)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.hasSlice() {
+		return nil, nil
+	}
+	if len(plan.keyColumns) == 0 { // 无主键时值相同的不同父级记录会被误合并
+		err = errors.WithMessagef(ErrNestedScanParentKey, "select primary key column of %s (db tag id or with pk option) to merge nested slices", elemType.String())
+		return nil, err
+	}
+	return plan, nil
+}
+
+// scanSlice 扫描全部行到结构体切片,父级主键相同的行合并为一条记录,嵌套切片元素依次追加
+func (plan *nestedScanPlan) scanSlice(rows Rows, slice reflect.Value) (rowsAffected int64, err error) {
+	elemType := slice.Type().Elem()
+	indexs := make(map[string]int)
+	for rows.Next() {
+		newElem := reflect.New(elemType).Elem()
+		err = plan.scanRow(rows, newElem)
+		if err != nil {
+			return rowsAffected, err
+		}
+		key := plan.parentKey(newElem)
+		if i, ok := indexs[key]; ok {
+			existing := slice.Index(i)
+			for _, target := range plan.targets {
+				if !target.isSlice {
+					continue
+				}
+				field := reflectx.FieldByIndexes(existing, target.traversal)
+				field.Set(reflect.AppendSlice(field, reflectx.FieldByIndexes(newElem, target.traversal)))
+			}
+			continue
+		}
+		indexs[key] = slice.Len()
+		slice.Set(reflect.Append(slice, newElem))
+		rowsAffected++
+	}
+	return rowsAffected, nil
+}
+
+// setScannedValue 将扫描到的值赋给嵌套结构体字段
+func setScannedValue(dst reflect.Value, v any) (err error) {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if scanner, ok := dst.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(v)
+	}
+	if b, ok := v.([]byte); ok && dst.Kind() != reflect.Slice {
+		v = string(b)
+	}
+	if dst.Kind() == reflect.Pointer {
+		ptr := reflect.New(dst.Type().Elem())
+		err = setScannedValue(ptr.Elem(), v)
+		if err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(cast.ToString(v))
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := cast.ToInt64E(v)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := cast.ToUint64E(v)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := cast.ToFloat64E(v)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		b, err := cast.ToBoolE(v)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+		return nil
+	}
+	if dst.Type() == reflect.TypeFor[time.Time]() {
+		t, err := cast.ToTimeE(v)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(rv.Convert(dst.Type()))
+		return nil
+	}
+	return errors.Errorf("can not convert %T to %s", v, dst.Type().String())
+}
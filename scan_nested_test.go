@@ -0,0 +1,126 @@
+package sqlbuilder_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+type nestedOrder struct {
+	Id         int    `db:"id"`
+	CustomerId int    `db:"customer_id"`
+	Title      string `db:"title"`
+}
+
+type nestedCustomer struct {
+	Id   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+type nestedItem struct {
+	Id   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+type nestedOrderView struct {
+	nestedOrder `db:"o"`
+	Customer    *nestedCustomer `db:"c"`
+	Items       []nestedItem    `db:"i"`
+}
+
+func NewNestedTitle(title string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(title, "title", "标题", 64)
+}
+
+var nestedScanTables = sqlbuilder.TableConfigs{
+	sqlbuilder.NewTableConfig("nested_order").AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("customer_id", sqlbuilder.GetField(NewSubQueryCustomerId)),
+		sqlbuilder.NewColumn("title", sqlbuilder.GetField(NewNestedTitle)),
+	).AddIndexs(fkPrimaryIndex),
+	sqlbuilder.NewTableConfig("nested_customer").AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+	).AddIndexs(fkPrimaryIndex),
+	sqlbuilder.NewTableConfig("nested_item").AddColumns(
+		sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+		sqlbuilder.NewColumn("order_id", sqlbuilder.GetField(NewRelOrderId)),
+		sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+	).AddIndexs(fkPrimaryIndex),
+}
+
+func newNestedScanDB(t *testing.T) *sql.DB {
+	db := sqlbuildertest.New(t, nestedScanTables...)
+	db.InsertFixtures(sqlbuildertest.Fixtures{
+		"nested_customer": {{"id": 10, "name": "alice"}},
+		"nested_order":    {{"id": 1, "customer_id": 10, "title": "first"}, {"id": 2, "customer_id": 99, "title": "second"}},
+		"nested_item":     {{"id": 100, "order_id": 1, "name": "a"}, {"id": 101, "order_id": 1, "name": "b"}},
+	})
+	return db.SqlDB()
+}
+
+func TestScanNested(t *testing.T) {
+	db := newNestedScanDB(t)
+
+	t.Run("prefixed columns", func(t *testing.T) {
+		rows, err := db.Query(`select o.id as "o.id", o.customer_id as "o.customer_id", o.title as "o.title", c.id as "c.id", c.name as "c.name", i.id as "i.id", i.name as "i.name"
+from nested_order o left join nested_customer c on c.id=o.customer_id left join nested_item i on i.order_id=o.id order by o.id,i.id`)
+		require.NoError(t, err)
+		views := make([]nestedOrderView, 0)
+		rowsAffected, err := sqlbuilder.Scan(rows, &views)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, rowsAffected)
+		require.Len(t, views, 2)
+		require.Equal(t, nestedOrder{Id: 1, CustomerId: 10, Title: "first"}, views[0].nestedOrder)
+		require.Equal(t, &nestedCustomer{Id: 10, Name: "alice"}, views[0].Customer)
+		require.Equal(t, []nestedItem{{Id: 100, Name: "a"}, {Id: 101, Name: "b"}}, views[0].Items)
+		require.Nil(t, views[1].Customer) // left join 无匹配
+		require.Empty(t, views[1].Items)
+	})
+
+	t.Run("table name prefix", func(t *testing.T) {
+		type view struct {
+			NestedOrder    nestedOrder
+			NestedCustomer nestedCustomer
+		}
+		rows, err := db.Query(`select nested_order.id as "nested_order.id", nested_customer.id as "nested_customer.id", nested_customer.name as "nested_customer.name"
+from nested_order join nested_customer on nested_customer.id=nested_order.customer_id`)
+		require.NoError(t, err)
+		v := view{}
+		rowsAffected, err := sqlbuilder.Scan(rows, &v)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, rowsAffected)
+		require.Equal(t, 1, v.NestedOrder.Id)
+		require.Equal(t, nestedCustomer{Id: 10, Name: "alice"}, v.NestedCustomer)
+	})
+
+	t.Run("parent key", func(t *testing.T) {
+		rows, err := db.Query(`select o.id as "o.id", 'same' as "o.title", i.id as "i.id", i.name as "i.name"
+from nested_order o left join nested_item i on i.order_id=o.id order by o.id,i.id`)
+		require.NoError(t, err)
+		views := make([]nestedOrderView, 0)
+		rowsAffected, err := sqlbuilder.Scan(rows, &views)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, rowsAffected) // 非主键列值相同的父级记录不合并
+		require.Len(t, views[0].Items, 2)
+		require.Empty(t, views[1].Items)
+
+		rows, err = db.Query(`select 'same' as "o.title", i.id as "i.id", i.name as "i.name"
+from nested_order o left join nested_item i on i.order_id=o.id order by o.id,i.id`)
+		require.NoError(t, err)
+		views = make([]nestedOrderView, 0)
+		_, err = sqlbuilder.Scan(rows, &views)
+		require.ErrorIs(t, err, sqlbuilder.ErrNestedScanParentKey)
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		rows, err := db.Query(`select o.id, c.id from nested_order o join nested_customer c on c.id=o.customer_id`)
+		require.NoError(t, err)
+		orders := make([]nestedOrder, 0)
+		_, err = sqlbuilder.Scan(rows, &orders)
+		require.ErrorIs(t, err, sqlbuilder.ErrAmbiguousColumn)
+	})
+}
@@ -0,0 +1,122 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidSort 排序参数格式错误或字段不在白名单
+var ErrInvalidSort = errors.New("invalid sort")
+
+const Field_name_sort = "_sort"
+
+// SortKey 排序键,Name 对应白名单 Field.Name
+type SortKey struct {
+	Name string
+	Desc bool
+}
+
+type SortKeys []SortKey
+
+// ParseSort 解析排序参数,逗号分隔,- 前缀降序、+ 前缀或无前缀升序,如 -createdAt,name
+func ParseSort(sort string) (keys SortKeys, err error) {
+	keys = make(SortKeys, 0)
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key := SortKey{Name: name}
+		switch name[0] {
+		case '-':
+			key.Desc = true
+			key.Name = name[1:]
+		case '+':
+			key.Name = name[1:]
+		}
+		key.Name = strings.TrimSpace(key.Name)
+		if key.Name == "" {
+			err = errors.WithMessagef(ErrInvalidSort, "empty sort key in:%s", sort)
+			return nil, err
+		}
+		if slices.ContainsFunc(keys, func(k SortKey) bool { return strings.EqualFold(k.Name, key.Name) }) {
+			err = errors.WithMessagef(ErrInvalidSort, "duplicate sort key:%s", key.Name)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// NewSortField 生成动态排序字段:sort 为空时使用 defaultSort,排序键必须在白名单 whitelist 中(按 Field.Name 匹配),
+// 排序列为白名单字段 DBColumnName().FullName(),最后追加未出现的主键列升序,保证分页顺序稳定;
+// 排序字段无值,不参与 where、select,可用于 ListParam、PaginationParam 及分表分页
+func NewSortField(sort string, whitelist Fields, defaultSort string) (f *Field, err error) {
+	if strings.TrimSpace(sort) == "" {
+		sort = defaultSort
+	}
+	keys, err := ParseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	sortFields := make(Fields, 0, len(keys))
+	for _, key := range keys {
+		allowed, ok := whitelist.GetByName(key.Name)
+		if !ok {
+			err = errors.WithMessagef(ErrInvalidSort, "sort field:%s not allowed", key.Name)
+			return nil, err
+		}
+		sortFields = append(sortFields, allowed.Copy())
+	}
+	f = &Field{Name: Field_name_sort}
+	f.SetOrderFn(func(f *Field, fs ...*Field) (orderedExpressions []exp.OrderedExpression) {
+		table := f.GetTable()
+		columnNames := make([]string, 0, len(keys))
+		for i, key := range keys {
+			dbName := sortFields[i].Copy().SetTable(table).DBColumnName()
+			columnNames = append(columnNames, dbName.BaseName())
+			identifier := goqu.I(dbName.FullName())
+			if key.Desc {
+				orderedExpressions = append(orderedExpressions, identifier.Desc())
+				continue
+			}
+			orderedExpressions = append(orderedExpressions, identifier.Asc())
+		}
+		primary, exists := table.Indexs.GetPrimary()
+		if !exists {
+			return orderedExpressions
+		}
+		for _, columnName := range primary.ColumnNames(table) { // 主键兜底,相同排序值的记录顺序稳定
+			if slices.Contains(columnNames, columnName) {
+				continue
+			}
+			fullName := fmt.Sprintf("%s.%s", table.BaseName(), columnName)
+			orderedExpressions = append(orderedExpressions, goqu.I(fullName).Asc())
+		}
+		return orderedExpressions
+	})
+	return f, nil
+}
+
+func newSortFieldOrError(sort string, whitelist Fields, defaultSort string) *Field {
+	f, err := NewSortField(sort, whitelist, defaultSort)
+	if err != nil {
+		return newErrorField(Field_name_sort, err) // 链式调用 WithSort 无法返回错误,生成 where 条件时返回
+	}
+	return f
+}
+
+// WithSort 按 API 排序参数(如 -createdAt,name)动态排序,排序键必须在白名单中,参数为空时使用 defaultSort,参数非法时查询返回 ErrInvalidSort
+func (p *ListParam) WithSort(sort string, whitelist Fields, defaultSort string) *ListParam {
+	return p.AppendFields(newSortFieldOrError(sort, whitelist, defaultSort))
+}
+
+// WithSort 同 ListParam.WithSort,分表分页按排序列跨分表归并
+func (p *PaginationParam) WithSort(sort string, whitelist Fields, defaultSort string) *PaginationParam {
+	return p.AppendFields(newSortFieldOrError(sort, whitelist, defaultSort))
+}
@@ -0,0 +1,64 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func TestSort(t *testing.T) {
+	db := sqlbuildertest.New(t, newUserTableConfig("sort_user_1"), newUserTableConfig("sort_user_2"))
+	db.InsertFixtures(sqlbuildertest.Fixtures{
+		"sort_user_1": {{"id": 1, "name": "b"}, {"id": 3, "name": "a"}, {"id": 5, "name": "b"}},
+		"sort_user_2": {{"id": 2, "name": "c"}, {"id": 4, "name": "a"}, {"id": 6, "name": "b"}},
+	})
+	table := db.Table("sort_user_1")
+	whitelist := sqlbuilder.Fields{NewIterateUserId(0), NewIterateUserName("")}
+
+	t.Run("list", func(t *testing.T) {
+		builder := sqlbuilder.NewListBuilder(table).WithSort("-name", whitelist, "")
+		sql, err := builder.ToSQL(builder.Fields())
+		require.NoError(t, err)
+		require.Contains(t, sql, "ORDER BY `sort_user_1`.`name` DESC, `sort_user_1`.`id` ASC")
+
+		users := make([]iterateUser, 0)
+		err = sqlbuilder.NewListBuilder(table).WithSort("", whitelist, "name,-id").List(&users)
+		require.NoError(t, err)
+		require.Equal(t, []iterateUser{{Id: 3, Name: "a"}, {Id: 5, Name: "b"}, {Id: 1, Name: "b"}}, users)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		users := make([]iterateUser, 0)
+		err := sqlbuilder.NewListBuilder(table).WithSort("password", whitelist, "").List(&users)
+		require.ErrorIs(t, err, sqlbuilder.ErrInvalidSort)
+		_, err = sqlbuilder.ParseSort("name,-")
+		require.ErrorIs(t, err, sqlbuilder.ErrInvalidSort)
+		_, err = sqlbuilder.ParseSort("name,-Name")
+		require.ErrorIs(t, err, sqlbuilder.ErrInvalidSort)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		users := make([]iterateUser, 0)
+		total, err := sqlbuilder.NewPaginationBuilder(table).WithSort("+name", whitelist, "").AppendFields(
+			NewPageIndex(0).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(2).SetTag(sqlbuilder.Field_tag_pageSize),
+		).Pagination(&users)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, total)
+		require.Equal(t, []iterateUser{{Id: 3, Name: "a"}, {Id: 1, Name: "b"}}, users)
+	})
+
+	t.Run("sharded pagination", func(t *testing.T) {
+		sharded := table.WithShardedTableNameFn(func(fs ...sqlbuilder.Field) (shardedTableNames []string) {
+			return []string{"sort_user_1", "sort_user_2"}
+		})
+		users := make([]iterateUser, 0)
+		total, err := sqlbuilder.NewPaginationBuilder(sharded).WithSort("name,-id", whitelist, "").AppendFields(
+			NewPageIndex(1).SetTag(sqlbuilder.Field_tag_pageIndex), NewPageSize(3).SetTag(sqlbuilder.Field_tag_pageSize),
+		).Pagination(&users)
+		require.NoError(t, err)
+		require.EqualValues(t, 6, total)
+		require.Equal(t, []iterateUser{{Id: 5, Name: "b"}, {Id: 1, Name: "b"}, {Id: 2, Name: "c"}}, users)
+	})
+}
@@ -0,0 +1,213 @@
+// Package sqlbuildertest 单元测试辅助:基于内存 sqlite 的 Handler、按 TableConfig 自动建表、夹具加载及断言
+package sqlbuildertest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/suifengpiao14/sqlbuilder"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures 夹具数据,表名 → 行记录,行记录以数据库列名(或字段名)为键
+type Fixtures map[string][]map[string]any
+
+// DB 内存 sqlite 测试库
+type DB struct {
+	tb      testing.TB
+	db      *sql.DB
+	handler sqlbuilder.Handler
+	tables  sqlbuilder.TableConfigs
+}
+
+var memoryDBSeq atomic.Int64
+
+// New 创建内存 sqlite 库并按 tables 建表,测试结束时自动关闭;返回的表配置已绑定该库句柄
+func New(tb testing.TB, tables ...sqlbuilder.TableConfig) *DB {
+	tb.Helper()
+	dsn := fmt.Sprintf("file:sqlbuildertest_%d?mode=memory&cache=shared", memoryDBSeq.Add(1)) // 每次独立的内存库
+	db, err := sql.Open(sqlbuilder.Driver_sqlite3.String(), dsn)
+	if err != nil {
+		tb.Fatalf("sqlbuildertest open sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	tb.Cleanup(func() { db.Close() })
+	d := &DB{
+		tb:      tb,
+		db:      db,
+		handler: sqlbuilder.NewFieldIDBHandler(func() *sql.DB { return db }),
+	}
+	for _, table := range tables {
+		ddl, err := sqlbuilder.GenerateDDL(sqlbuilder.Driver_sqlite3, table)
+		if err != nil {
+			tb.Fatalf("sqlbuildertest generate ddl table:%s: %v", table.Name, err)
+		}
+		if _, err = db.Exec(ddl); err != nil {
+			tb.Fatalf("sqlbuildertest create table:%s: %v\nddl:%s", table.Name, err, ddl)
+		}
+		d.tables = append(d.tables, table.WithHandler(d.handler))
+	}
+	return d
+}
+
+// Handler 测试库句柄
+func (d *DB) Handler() sqlbuilder.Handler {
+	return d.handler
+}
+
+// SqlDB 测试库原始连接,便于直接执行sql准备数据
+func (d *DB) SqlDB() *sql.DB {
+	return d.db
+}
+
+// Tables 绑定测试库句柄的表配置
+func (d *DB) Tables() sqlbuilder.TableConfigs {
+	return d.tables
+}
+
+// Table 按表名获取绑定测试库句柄的表配置,不存在时测试失败
+func (d *DB) Table(name string) sqlbuilder.TableConfig {
+	d.tb.Helper()
+	table, ok := d.tables.GetByName(name)
+	if !ok {
+		d.tb.Fatalf("sqlbuildertest table:%s not found", name)
+	}
+	return *table
+}
+
+// LoadFixtures 加载夹具文件(.yaml/.yml/.json),文件内容为 表名 → 行记录列表
+func (d *DB) LoadFixtures(filenames ...string) {
+	d.tb.Helper()
+	for _, filename := range filenames {
+		fixtures, err := ReadFixtures(filename)
+		if err != nil {
+			d.tb.Fatalf("sqlbuildertest %v", err)
+		}
+		d.InsertFixtures(fixtures)
+	}
+}
+
+// InsertFixtures 通过 BatchInsertParam 写入夹具数据,按表名顺序写入
+func (d *DB) InsertFixtures(fixtures Fixtures) {
+	d.tb.Helper()
+	tableNames := make([]string, 0, len(fixtures))
+	for tableName := range fixtures {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		rows := fixtures[tableName]
+		if len(rows) == 0 {
+			continue
+		}
+		table := d.Table(tableName)
+		rowFields := make([]sqlbuilder.Fields, 0, len(rows))
+		for _, row := range rows {
+			fs, err := rowToFields(table, row)
+			if err != nil {
+				d.tb.Fatalf("sqlbuildertest fixtures table:%s: %v", tableName, err)
+			}
+			rowFields = append(rowFields, fs)
+		}
+		err := sqlbuilder.NewBatchInsertBuilder(table).AppendFields(rowFields...).Exec()
+		if err != nil {
+			d.tb.Fatalf("sqlbuildertest insert fixtures table:%s: %v", tableName, err)
+		}
+	}
+}
+
+// ReadFixtures 读取夹具文件,按扩展名解析 yaml 或 json
+func ReadFixtures(filename string) (fixtures Fixtures, err error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fixtures = Fixtures{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &fixtures)
+	case ".json":
+		err = json.Unmarshal(b, &fixtures)
+	default:
+		err = errors.Errorf("unsupported fixtures file:%s, required .yaml/.yml/.json", filename)
+	}
+	if err != nil {
+		err = errors.WithMessagef(err, "fixtures file:%s", filename)
+		return nil, err
+	}
+	return fixtures, nil
+}
+
+// AssertRowExists 断言存在满足 where(列名 → 值,等值条件)的记录
+func AssertRowExists(tb testing.TB, table sqlbuilder.TableConfig, where map[string]any) {
+	tb.Helper()
+	fs, err := whereToFields(table, where)
+	if err != nil {
+		tb.Fatalf("sqlbuildertest AssertRowExists table:%s: %v", table.Name, err)
+	}
+	exists, err := sqlbuilder.NewExistsBuilder(table).AppendFields(fs...).Exists()
+	if err != nil {
+		tb.Fatalf("sqlbuildertest AssertRowExists table:%s: %v", table.Name, err)
+	}
+	if !exists {
+		tb.Errorf("sqlbuildertest AssertRowExists table:%s where:%v: row not found", table.Name, where)
+	}
+}
+
+// AssertRowCount 断言满足 where(列名 → 值,等值条件)的记录数,where 为空时统计全表
+func AssertRowCount(tb testing.TB, table sqlbuilder.TableConfig, expected int64, where map[string]any) {
+	tb.Helper()
+	fs, err := whereToFields(table, where)
+	if err != nil {
+		tb.Fatalf("sqlbuildertest AssertRowCount table:%s: %v", table.Name, err)
+	}
+	total, err := sqlbuilder.NewTotalBuilder(table).AppendFields(fs...).Count()
+	if err != nil {
+		tb.Fatalf("sqlbuildertest AssertRowCount table:%s: %v", table.Name, err)
+	}
+	if total != expected {
+		tb.Errorf("sqlbuildertest AssertRowCount table:%s where:%v: expected %d,got %d", table.Name, where, expected, total)
+	}
+}
+
+func getColumn(table sqlbuilder.TableConfig, name string) (col sqlbuilder.ColumnConfig, err error) {
+	if col, ok := table.Columns.GetByDbName(name); ok {
+		return col, nil
+	}
+	if col, ok := table.Columns.GetByFieldName(name); ok {
+		return col, nil
+	}
+	err = errors.Errorf("column:%s not found in table:%s", name, table.Name)
+	return col, err
+}
+
+func rowToFields(table sqlbuilder.TableConfig, row map[string]any) (fs sqlbuilder.Fields, err error) {
+	for name, value := range row {
+		col, err := getColumn(table, name)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, col.MakeField(value))
+	}
+	return fs, nil
+}
+
+func whereToFields(table sqlbuilder.TableConfig, where map[string]any) (fs sqlbuilder.Fields, err error) {
+	for name, value := range where {
+		col, err := getColumn(table, name)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, col.MakeField(value).AppendWhereFn(sqlbuilder.ValueFnForward))
+	}
+	return fs, nil
+}
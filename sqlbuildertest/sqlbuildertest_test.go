@@ -0,0 +1,52 @@
+package sqlbuildertest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func NewUserId(id int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(id, "id", "ID", 0)
+}
+
+func NewUserName(name string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(name, "name", "名称", 64)
+}
+
+var userTable = sqlbuilder.NewTableConfig("fixture_user").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewUserId)),
+	sqlbuilder.NewColumn("user_name", sqlbuilder.GetField(NewUserName)),
+).AddIndexs(sqlbuilder.Index{
+	IsPrimary: true,
+	ColumnNames: func(table sqlbuilder.TableConfig) (columnNames []string) {
+		return []string{"id"}
+	},
+})
+
+func TestDB(t *testing.T) {
+	db := sqlbuildertest.New(t, userTable)
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "users.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("fixture_user:\n  - id: 1\n    user_name: alice\n  - id: 2\n    name: bob\n"), 0o644))
+	jsonFile := filepath.Join(dir, "users.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`{"fixture_user":[{"id":3,"user_name":"it's"}]}`), 0o644))
+	db.LoadFixtures(yamlFile, jsonFile)
+
+	table := db.Table("fixture_user")
+	sqlbuildertest.AssertRowCount(t, table, 3, nil)
+	sqlbuildertest.AssertRowExists(t, table, map[string]any{"id": 2, "user_name": "bob"})
+	sqlbuildertest.AssertRowExists(t, table, map[string]any{"name": "it's"})
+
+	err := table.Repository().Insert(context.Background(), sqlbuilder.Fields{NewUserId(4), NewUserName("carol")})
+	require.NoError(t, err)
+	sqlbuildertest.AssertRowCount(t, table, 1, map[string]any{"name": "carol"})
+
+	other := sqlbuildertest.New(t, userTable) // 每次独立的内存库
+	sqlbuildertest.AssertRowCount(t, other.Table("fixture_user"), 0, nil)
+}
@@ -111,16 +111,20 @@ type TableConfig struct {
 	Columns                  ColumnConfigs            // 后续吧table 纳入，通过 Column.Identity 生成 Field 操作
 	FieldName2DBColumnNameFn FieldName2DBColumnNameFn `json:"-"`
 	Schema                   SchemaConfig
-	_handler                 Handler // 内部获取，使用GetHandler方法（GetHander 方法挂载一些初始化动作）
-	Comment                  string  // 表注释
-	Indexs                   Indexs  // 索引信息，唯一索引，在新增时会自动校验是否存在,更新时会自动保护
+	_handler                 Handler     // 内部获取，使用GetHandler方法（GetHander 方法挂载一些初始化动作）
+	Comment                  string      // 表注释
+	Indexs                   Indexs      // 索引信息，唯一索引，在新增时会自动校验是否存在,更新时会自动保护
+	ForeignKeys              ForeignKeys // 外键信息,GenerateDDL 时生成外键约束,CheckExists 的外键在新增、更新前校验引用记录存在
+	Relations                Relations   // 表关系,ListParam.Preload、FirstParam.Preload 按名称批量加载关联数据
 	// 表级别的字段（值产生方式和实际数据无关），比如创建时间、更新时间、删除字段等，这些字段设置好后，相关操作可从此获取字段信息,增加该字段，方便封装delete操作、冗余字段自动填充等操作, 增加ctx 入参 方便使用ctx专递数据，比如 业务扩展多租户，只需表增加相关字段，在ctx中传递租户信息，并设置表级别字段场景即可
 	// 比如规则模型，表中cityId,classId,productId 字段只是方便后台查询设置,api 侧只需要规则表达式(expresson)即可,expresson 往往是其它字段按照按照需求生成的字符串,
 	//此时使用hook确保关注的字段发生变化时，自动更新冗余数据(在构造sql 的Fields 内追加冗余字段Field)
 	//Deprecated: 废弃hook,hook只能修改写入数据库前,无法在操作数据库之后再修改. 包内引用了middleware概念,可以使用middleware统一实现
-	tableLevelFieldsHook HookFn
-	modelMiddlewares     ModelMiddlewares
-	shardedTableNameFn   func(fs ...Field) (shardedTableNames []string) // 分表策略，比如按时间分表，此处传入字段信息，返回多个表名
+	tableLevelFieldsHook   HookFn
+	modelMiddlewares       ModelMiddlewares
+	shardedTableNameFn     func(fs ...Field) (shardedTableNames []string) // 分表策略，比如按时间分表，此处传入字段信息，返回多个表名
+	shardedTableAutoCreate bool                                           // 写入分表时分表不存在则自动创建
+	skipUniqueCheck        bool                                           // 跳过唯一索引 exists 预检查,由数据库唯一约束保证
 	//publisher            message.Publisher table 只和gochannel publisher 交互，不直接和外部交互，如果需要发布到外部(如mq,kafka等)时，监听内部gochannel 转发即可，这样设计的目的是将领域内事件和领域外事件分离，方便内聚和聚合
 	comsumerMakers []func(table TableConfig) Consumer // 当前表级别的消费者(主要用于在表级别同步数据)
 	//views          TableConfigs view概念没有用 table在这里不是一等公民,Field才是一等公民,view功能通过FieldsI 接口实现,并且更合适
@@ -382,6 +386,19 @@ func (t TableConfig) WithShardedTableNameFn(shardedTableNameFn func(fs ...Field)
 	t.shardedTableNameFn = shardedTableNameFn
 	return t
 }
+
+// WithShardedTableAutoCreate 写入分表时分表不存在则自动创建,如按月分表时新月份的首次写入
+func (t TableConfig) WithShardedTableAutoCreate(autoCreate bool) TableConfig {
+	t.shardedTableAutoCreate = autoCreate
+	return t
+}
+
+// WithSkipUniqueCheck 跳过 CheckUniqueIndex 的 exists 预检查(并发时预检查存在竞态),由数据库唯一约束保证,冲突时返回 ErrDuplicateKey
+func (t TableConfig) WithSkipUniqueCheck(skip bool) TableConfig {
+	t.skipUniqueCheck = skip
+	return t
+}
+
 func (t TableConfig) getShardedTableNames(fs ...Field) (shardedTableNames []string) {
 	if t.shardedTableNameFn == nil {
 		return nil
@@ -443,6 +460,9 @@ func (t TableConfig) GetHandler() (handler Handler) {
 
 func (t TableConfig) GetHandlerWithInitTable() (handler Handler) {
 	handler = t.GetHandler()
+	if isShardedHandler(handler) || isDryRunHandler(handler) || isReplayHandler(handler) { // 分库句柄需路由到具体分库后再初始化,试运行、回放句柄不初始化
+		return handler
+	}
 	if shouldCrateTable(t.Name, Driver(handler.GetDialector())) {
 		sql := fmt.Sprintf(`select 1 from %s;`, t.DBName.BaseNameWithQuotes())
 		ctx := context.Background()
@@ -505,6 +525,9 @@ func (t TableConfig) GetFieldNameByAlaisFeild(alaisField *Field) (fieldName stri
 var Error_UniqueIndexAlreadyExist = errors.New("unique index already exist")
 
 func (t TableConfig) CheckUniqueIndex(allFields ...*Field) (err error) {
+	if t.skipUniqueCheck {
+		return nil
+	}
 	indexs := t.Indexs.GetUnique()
 	for _, index := range indexs {
 		uFs := index.Fields(t, allFields).AppendWhereValueFn(ValueFnForward) // 变成查询条件
@@ -512,6 +535,9 @@ func (t TableConfig) CheckUniqueIndex(allFields ...*Field) (err error) {
 		if len(uFs) != len(columnNames) { // 如果唯一标识字段数量和筛选条件字段数量不一致，则忽略该唯一索引校验（如 update 时不涉及到指定唯一索引）
 			continue
 		}
+		if slices.ContainsFunc(uFs, func(f *Field) bool { return IsNullValue(f.GetOriginalValue()) }) { // 唯一索引允许多个 NULL
+			continue
+		}
 		exists, err := NewExistsBuilder(t).WithHandler(t.GetHandler()).AppendFields(uFs...).Exists()
 		if err != nil {
 			return err
@@ -663,6 +689,12 @@ func (t TableConfig) Merge(tables ...TableConfig) TableConfig {
 		if table.shardedTableNameFn != nil {
 			t.shardedTableNameFn = table.shardedTableNameFn
 		}
+		if table.shardedTableAutoCreate {
+			t.shardedTableAutoCreate = table.shardedTableAutoCreate
+		}
+		if table.skipUniqueCheck {
+			t.skipUniqueCheck = table.skipUniqueCheck
+		}
 		if table.comsumerMakers != nil {
 			t.comsumerMakers = append(t.comsumerMakers, table.comsumerMakers...)
 		}
@@ -713,7 +745,7 @@ func (c ColumnConfig) WithLength(length int) ColumnConfig {
 	return c
 }
 func (c ColumnConfig) WithNullable(nullable bool) ColumnConfig {
-	c.NotNull = nullable
+	c.NotNull = !nullable
 	return c
 }
 func (c ColumnConfig) WithUnSigned(unsigned bool) ColumnConfig {
@@ -1178,7 +1210,7 @@ func GetRecordForUpdate[Model any](fs Fields) (record *Model, err error) {
 		return nil, err
 	}
 	if len(records) == 0 {
-		err = errors.Errorf("没有查询到记录")
+		err = errors.WithMessage(ErrNotFound, "没有查询到记录")
 
 		return nil, err
 	}
@@ -0,0 +1,138 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// WithOnlyChanged 只更新值有变化的列:执行前查询当前记录(where 条件需唯一确定一条记录),按 Layer_get_value_before_db 后的值与记录比较,
+// 未变化列不写入,全部未变化时不执行更新(不触发更新事件),变更列(DB 列名)通过 FieldName_changedColumns 字段传递给模型中间件;
+// updatedAt 列不参与比较,仅随其它列变化写入;分表更新返回 ErrOnlyChangedSharded
+func (p *UpdateParam) WithOnlyChanged(onlyChanged bool) *UpdateParam {
+	p.onlyChanged = onlyChanged
+	return p
+}
+
+// ErrOnlyChangedSharded 分表不支持只更新变化列(各分表记录不同,无法确定唯一记录)
+var ErrOnlyChangedSharded = errors.New("only changed update not supported on sharded table")
+
+// onlyChangedFields 未变化的字段屏蔽更新(仍可作为 where 条件),返回变更列(按列名排序)
+func (p UpdateParam) onlyChangedFields(fs Fields) (changedFs Fields, changedColumns []string, err error) {
+	table := p.GetTable()
+	builtFs := fs.Builder(p.context, SCENE_SQL_UPDATE, table, p.customFieldsFns)
+	updatingData, dbRecord, err := builtFs.GetChangingData()
+	if errors.Is(err, ErrNotFound) && !p.mustExists { // 记录不存在,无需更新
+		return fs, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	changedColumns = make([]string, 0, len(updatingData))
+	unchanged := make(map[string]bool)
+	for column, val := range updatingData {
+		col, _ := table.Columns.GetByDbName(column)
+		if col.Tags.HastTag(Tag_updatedAt) {
+			continue
+		}
+		if isSameDBValue(col, val, dbRecord[column]) {
+			unchanged[column] = true
+			continue
+		}
+		changedColumns = append(changedColumns, column)
+	}
+	sort.Strings(changedColumns)
+	changedFs = make(Fields, 0, len(fs))
+	for _, f := range fs {
+		if unchanged[f.Copy().SetTable(table).DBColumnName().BaseName()] {
+			f = f.Copy().ShieldUpdate(true)
+		}
+		changedFs = append(changedFs, f)
+	}
+	return changedFs, changedColumns, nil
+}
+
+// isSameDBValue 比较更新值与数据库记录值,驱动返回类型不一(int64、[]byte、time.Time 等),按列类型归一后比较:
+// 时间按年月日时分秒、小数按数值、布尔按真假比较,其余及转换失败时按字符串比较
+func isSameDBValue(col ColumnConfig, val any, dbVal any) bool {
+	val, dbVal = bytesToString(val), bytesToString(dbVal)
+	if isNilOrNullValue(val) || isNilOrNullValue(dbVal) {
+		return isNilOrNullValue(val) && isNilOrNullValue(dbVal)
+	}
+	switch columnCompareKind(col) {
+	case compareKind_time:
+		t1, err1 := cast.ToTimeE(val)
+		t2, err2 := cast.ToTimeE(dbVal)
+		if err1 == nil && err2 == nil {
+			return t1.Format(time.DateTime) == t2.Format(time.DateTime) // 按各自时区的年月日时分秒比较,与写入 db 的字面值一致
+		}
+	case compareKind_float:
+		f1, err1 := cast.ToFloat64E(val)
+		f2, err2 := cast.ToFloat64E(dbVal)
+		if err1 == nil && err2 == nil {
+			return f1 == f2
+		}
+	case compareKind_bool:
+		b1, err1 := cast.ToBoolE(val)
+		b2, err2 := cast.ToBoolE(dbVal)
+		if err1 == nil && err2 == nil {
+			return b1 == b2
+		}
+	}
+	toString := func(v any) string {
+		str, err := cast.ToStringE(v)
+		if err != nil {
+			str = fmt.Sprint(v)
+		}
+		return str
+	}
+	return toString(val) == toString(dbVal)
+}
+
+const (
+	compareKind_string = "string"
+	compareKind_time   = "time"
+	compareKind_float  = "float"
+	compareKind_bool   = "bool"
+)
+
+// columnCompareKind 列值比较方式,按列类型(如 datetime、decimal(10,2)、tinyint(1)),未识别时按字段 Schema.Format 判断时间
+func columnCompareKind(col ColumnConfig) string {
+	typ := strings.ToLower(strings.TrimSpace(col.Type.String()))
+	if typ == "tinyint(1)" {
+		return compareKind_bool
+	}
+	if i := strings.IndexAny(typ, "( "); i > 0 {
+		typ = typ[:i]
+	}
+	switch typ {
+	case "datetime", "timestamp", "date", "time":
+		return compareKind_time
+	case "decimal", "numeric", "float", "float64", "double", "real":
+		return compareKind_float
+	case "bool", "boolean":
+		return compareKind_bool
+	}
+	if f := col.GetField(); f != nil && f.Schema != nil {
+		switch f.Schema.Format {
+		case Schema_format_dateTime, Schema_format_date:
+			return compareKind_time
+		}
+	}
+	return compareKind_string
+}
+
+func bytesToString(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+func isNilOrNullValue(v any) bool {
+	return IsNil(v) || IsNullValue(v)
+}
@@ -0,0 +1,127 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+	"github.com/suifengpiao14/sqlbuilder/sqlbuildertest"
+)
+
+func NewChangedUpdatedAt(updatedAt string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(updatedAt, "updatedAt", "更新时间", 0)
+}
+
+func NewChangedBirthday(birthday string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(birthday, "birthday", "生日", 0).MergeSchema(sqlbuilder.Schema{Format: sqlbuilder.Schema_format_dateTime})
+}
+
+func NewChangedBalance(balance string) *sqlbuilder.Field {
+	return sqlbuilder.NewStringField(balance, "balance", "余额", 0)
+}
+
+func NewChangedEnabled(enabled int) *sqlbuilder.Field {
+	return sqlbuilder.NewIntField(enabled, "enabled", "是否启用", 0)
+}
+
+var changedUserTable = sqlbuilder.NewTableConfig("changed_user").AddColumns(
+	sqlbuilder.NewColumn("id", sqlbuilder.GetField(NewIterateUserId)),
+	sqlbuilder.NewColumn("name", sqlbuilder.GetField(NewIterateUserName)),
+	sqlbuilder.NewColumn("nickname", sqlbuilder.GetField(NewNullUserNickname)),
+	sqlbuilder.NewColumn("birthday", sqlbuilder.GetField(NewChangedBirthday)).WithType("time"), // sqlite DATETIME,驱动返回 time.Time
+	sqlbuilder.NewColumn("balance", sqlbuilder.GetField(NewChangedBalance)).WithType("decimal(10,2)"),
+	sqlbuilder.NewColumn("enabled", sqlbuilder.GetField(NewChangedEnabled)).WithType("bool"),
+	sqlbuilder.NewColumn("updated_at", sqlbuilder.GetField(NewChangedUpdatedAt)).WithTags(sqlbuilder.Tag_updatedAt),
+).AddIndexs(fkPrimaryIndex)
+
+func newOnlyChangedTable(t *testing.T, changedColumns *[]string) sqlbuilder.TableConfig {
+	db := sqlbuildertest.New(t, changedUserTable)
+	db.InsertFixtures(sqlbuildertest.Fixtures{"changed_user": {
+		{"id": 1, "name": "user1", "nickname": "nick1", "birthday": "2000-01-02 03:04:05", "balance": "10.50", "enabled": 1, "updated_at": "2024-01-01 00:00:00"},
+	}})
+	return db.Table("changed_user").WithModelMiddlewares(sqlbuilder.ModelMiddleware{
+		Name: "changedColumns",
+		Fn: func(ctx *sqlbuilder.ModelMiddlewareContext, fs *sqlbuilder.Fields) (err error) {
+			err = ctx.Next(fs)
+			if err != nil {
+				return err
+			}
+			if f, ok := fs.GetByName(sqlbuilder.FieldName_changedColumns); ok {
+				*changedColumns = f.GetOriginalValue().([]string)
+			}
+			return nil
+		},
+	})
+}
+
+func TestUpdateOnlyChanged(t *testing.T) {
+	var changedColumns []string
+	table := newOnlyChangedTable(t, &changedColumns)
+	whereId := func(id int) *sqlbuilder.Field {
+		return NewIterateUserId(id).AppendWhereFn(sqlbuilder.ValueFnForward).ShieldUpdate(true)
+	}
+	record := func() map[string]any {
+		row := make(map[string]any)
+		exists, err := sqlbuilder.NewFirstBuilder(table).AppendFields(whereId(1)).First(&row)
+		require.NoError(t, err)
+		require.True(t, exists)
+		return row
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).WithOnlyChanged(true).AppendFields(
+			NewIterateUserName("user1"), NewChangedUpdatedAt("2024-02-02 00:00:00"), whereId(1),
+		).Update()
+		require.NoError(t, err)
+		require.EqualValues(t, 0, rowsAffected)
+		require.Empty(t, changedColumns)
+		require.Equal(t, "2024-01-01 00:00:00", record()["updated_at"]) // 无变化不执行更新
+	})
+
+	t.Run("typed columns unchanged", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).WithOnlyChanged(true).AppendFields(
+			NewChangedBirthday("2000-01-02 03:04:05"), NewChangedBalance("10.5"), NewChangedEnabled(1), whereId(1),
+		).Update()
+		require.NoError(t, err)
+		require.EqualValues(t, 0, rowsAffected)
+		require.Empty(t, changedColumns)
+	})
+
+	t.Run("typed columns changed", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).WithOnlyChanged(true).AppendFields(
+			NewChangedBirthday("2000-01-02 03:04:06"), NewChangedBalance("10.51"), NewChangedEnabled(1), whereId(1),
+		).Update()
+		require.NoError(t, err)
+		require.EqualValues(t, 1, rowsAffected)
+		require.Equal(t, []string{"balance", "birthday"}, changedColumns)
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).WithOnlyChanged(true).AppendFields(
+			NewIterateUserName("user1"), NewNullUserNickname("").SetValue(sqlbuilder.Null), NewChangedUpdatedAt("2024-02-02 00:00:00"), whereId(1),
+		).Update()
+		require.NoError(t, err)
+		require.EqualValues(t, 1, rowsAffected)
+		require.Equal(t, []string{"nickname"}, changedColumns)
+		row := record()
+		require.Nil(t, row["nickname"])
+		require.Equal(t, "2024-02-02 00:00:00", row["updated_at"])
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).WithOnlyChanged(true).AppendFields(NewIterateUserName("x"), whereId(9)).Update()
+		require.NoError(t, err)
+		require.EqualValues(t, 0, rowsAffected)
+
+		_, err = sqlbuilder.NewUpdateBuilder(table).WithOnlyChanged(true).WithMustExists(true).AppendFields(NewIterateUserName("x"), whereId(9)).Update()
+		require.ErrorIs(t, err, sqlbuilder.ErrNotFound)
+	})
+
+	t.Run("sharded", func(t *testing.T) {
+		sharded := table.WithShardedTableNameFn(func(fs ...sqlbuilder.Field) (shardedTableNames []string) {
+			return []string{"changed_user"}
+		})
+		_, err := sqlbuilder.NewUpdateBuilder(sharded).WithOnlyChanged(true).AppendFields(NewIterateUserName("x"), whereId(1)).Update()
+		require.ErrorIs(t, err, sqlbuilder.ErrOnlyChangedSharded)
+	})
+}
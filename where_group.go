@@ -0,0 +1,73 @@
+package sqlbuilder
+
+import (
+	"github.com/doug-martin/goqu/v9"
+)
+
+// WhereGroupType where 条件分组组合方式
+type WhereGroupType string
+
+const (
+	WhereGroupType_and WhereGroupType = "AND"
+	WhereGroupType_or  WhereGroupType = "OR"
+	WhereGroupType_not WhereGroupType = "NOT" // 子条件 and 后取反
+)
+
+const Field_name_whereGroup = "_whereGroup"
+
+// NewWhereGroupField 生成 where 条件分组字段,如 (a=1 or b=2) and c=3:
+// 子字段保留各自 WhereFns,where 值为 nil 的子字段忽略,全部忽略时整组忽略;子字段可以是分组字段实现嵌套;
+// 分组字段本身无值,不参与 insert、update 数据及 select 列,子字段未设置表、场景时继承分组字段的表、场景,可用于 ListParam、TotalParam、UpdateParam、DeleteParam 等
+func NewWhereGroupField(groupType WhereGroupType, fields ...*Field) (f *Field) {
+	children := Fields(fields).Copy() // 复制,不受调用方后续修改影响
+	f = &Field{Name: Field_name_whereGroup}
+	f.WhereFns.Append(ValueFn{
+		Layer:       Value_Layer_DBFormat,
+		Description: "where 条件分组",
+		Fn: func(_ any, f *Field, fs ...*Field) (any, error) {
+			expressions := make(Expressions, 0, len(children))
+			for _, child := range children {
+				child = child.Copy().SetTable(f.GetTable()).SetSceneIfEmpty(f.GetScene())
+				subExprs, err := child.Where(fs...)
+				if err != nil {
+					return nil, err
+				}
+				if len(subExprs) == 0 {
+					continue
+				}
+				expressions = append(expressions, goqu.And(subExprs...))
+			}
+			if len(expressions) == 0 {
+				return nil, nil
+			}
+			switch groupType {
+			case WhereGroupType_or:
+				return goqu.Or(expressions...), nil
+			case WhereGroupType_not:
+				return goqu.L("NOT ?", goqu.And(expressions...)), nil
+			}
+			return goqu.And(expressions...), nil
+		},
+	})
+	return f
+}
+
+// NewOrField 子字段 where 条件 or 组合
+func NewOrField(fields ...*Field) *Field {
+	return NewWhereGroupField(WhereGroupType_or, fields...)
+}
+
+// NewAndField 子字段 where 条件 and 组合,一般用于嵌套在 NewOrField 中
+func NewAndField(fields ...*Field) *Field {
+	return NewWhereGroupField(WhereGroupType_and, fields...)
+}
+
+// NewNotField 子字段 where 条件 and 组合后取反
+func NewNotField(fields ...*Field) *Field {
+	return NewWhereGroupField(WhereGroupType_not, fields...)
+}
+
+// WhereOr 当前字段集合 where 条件 or 组合为一个分组字段
+func (fs Fields) WhereOr() *Field {
+	return NewOrField(fs...)
+}
@@ -0,0 +1,81 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/sqlbuilder"
+)
+
+func TestWhereGroup(t *testing.T) {
+	table := newIterateTable(t, 5)
+	idWhere := func(id int) *sqlbuilder.Field {
+		return NewIterateUserId(id).AppendWhereFn(sqlbuilder.ValueFnForward)
+	}
+	nameWhere := func(name string) *sqlbuilder.Field {
+		return NewIterateUserName(name).AppendWhereFn(sqlbuilder.ValueFnEmpty2Nil, sqlbuilder.ValueFnForward)
+	}
+
+	t.Run("or", func(t *testing.T) {
+		sql, err := sqlbuilder.NewListBuilder(table).ToSQL(sqlbuilder.Fields{
+			sqlbuilder.NewOrField(idWhere(1), nameWhere("user2")),
+			NewIterateUserName("user1").AppendWhereFn(sqlbuilder.ValueFnNotEqual),
+		})
+		require.NoError(t, err)
+		require.Contains(t, sql, "WHERE (((`iterate_user`.`id` = 1) OR (`iterate_user`.`name` = 'user2')) AND (`iterate_user`.`name` != 'user1'))")
+		users := make([]iterateUser, 0)
+		err = sqlbuilder.NewListBuilder(table).AppendFields(
+			sqlbuilder.NewOrField(idWhere(1), nameWhere("user2"), idWhere(4)),
+			NewIterateUserName("user1").AppendWhereFn(sqlbuilder.ValueFnNotEqual),
+			NewIterateUserId(0).SetOrderFn(sqlbuilder.OrderFnAsc),
+		).List(&users)
+		require.NoError(t, err)
+		require.Equal(t, []iterateUser{{Id: 2, Name: "user2"}, {Id: 4, Name: "user4"}}, users)
+	})
+
+	t.Run("nested and nil ignored", func(t *testing.T) {
+		total, err := sqlbuilder.NewTotalBuilder(table).AppendFields(
+			sqlbuilder.NewOrField(
+				sqlbuilder.NewAndField(idWhere(1), nameWhere("")),
+				sqlbuilder.NewAndField(idWhere(3), nameWhere("user3")),
+				sqlbuilder.NewNotField(nameWhere("")), // 子条件均为空,整组忽略
+			),
+		).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+
+		total, err = sqlbuilder.NewTotalBuilder(table).AppendFields(sqlbuilder.NewOrField(nameWhere(""))).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 5, total)
+	})
+
+	t.Run("not", func(t *testing.T) {
+		total, err := sqlbuilder.NewTotalBuilder(table).AppendFields(
+			sqlbuilder.NewNotField(sqlbuilder.Fields{idWhere(1), idWhere(2)}.WhereOr()),
+		).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 3, total)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		rowsAffected, err := sqlbuilder.NewUpdateBuilder(table).AppendFields(
+			sqlbuilder.NewOrField(idWhere(4), idWhere(5)),
+			NewIterateUserName("updated"),
+		).Update()
+		require.NoError(t, err)
+		require.EqualValues(t, 2, rowsAffected)
+		total, err := sqlbuilder.NewTotalBuilder(table).AppendFields(nameWhere("updated")).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		softTable := table.AddColumns(sqlbuilder.NewColumn("deleted_at", sqlbuilder.GetField(NewFkDeletedAt)))
+		sql, err := sqlbuilder.NewDeleteBuilder(softTable).ToSQL(sqlbuilder.Fields{
+			sqlbuilder.NewOrField(idWhere(1), idWhere(2)),
+			NewFkDeletedAt("2026-01-01 00:00:00").SetFieldName(sqlbuilder.Field_name_deletedAt),
+		})
+		require.NoError(t, err)
+		require.Contains(t, sql, "WHERE ((`iterate_user`.`id` = 1) OR (`iterate_user`.`id` = 2))")
+	})
+}